@@ -0,0 +1,113 @@
+package arg
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ParseWithBootstrap processes command line arguments in two passes. The
+// first pass sets only the options tagged with bootstrap, from the command
+// line and the environment, and ignores all other arguments. It then calls
+// the bootstrap function, which can use those options to prepare for the
+// second pass, for example by activating a profile or setting defaults. The
+// bootstrap options are then reset to the values they had before the first
+// pass, and the second pass processes all arguments in the same way as Parse.
+func (p *Parser) ParseWithBootstrap(args []string, bootstrap func() error) error {
+	// remember the values of the bootstrap options so that the second pass
+	// starts from them again, rather than appending to lists a second time
+	var specs []*spec
+	var saved []reflect.Value
+	for _, spec := range p.cmd.specs {
+		if spec.bootstrap {
+			v := reflect.New(spec.typ).Elem()
+			v.Set(p.val(spec.dest))
+			specs = append(specs, spec)
+			saved = append(saved, v)
+		}
+	}
+
+	if err := p.parseBootstrap(args); err != nil {
+		return err
+	}
+	if err := bootstrap(); err != nil {
+		return err
+	}
+	for i, spec := range specs {
+		p.val(spec.dest).Set(saved[i])
+	}
+	return p.Parse(args)
+}
+
+// parseBootstrap sets the options tagged with bootstrap, ignoring unknown
+// options, positionals and subcommands
+func (p *Parser) parseBootstrap(args []string) error {
+	var specs []*spec
+	for _, spec := range p.cmd.specs {
+		if spec.bootstrap {
+			specs = append(specs, spec)
+		}
+	}
+	if len(specs) == 0 {
+		return nil
+	}
+
+	// the second pass prints the same warnings again
+	p.quiet = true
+	defer func() { p.quiet = false }()
+
+	wasPresent := make(map[*spec]bool)
+	if err := p.loadEnvFiles(); err != nil {
+		return err
+	}
+	if err := p.loadValueDirs(); err != nil {
+		return err
+	}
+	if err := p.captureEnvironment(specs, wasPresent); err != nil {
+		return err
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !isFlag(arg) {
+			continue
+		}
+
+		opt, value := splitOption(arg)
+		spec := findOption(specs, opt)
+		if spec == nil {
+			continue
+		}
+		var err error
+		if i, err = p.setOption(spec, arg, value, args, i); err != nil {
+			return err
+		}
+	}
+
+	if p.config.ValueDirPrecedence == ValueDirAboveCommandLine {
+		return p.captureValueDirs(specs, wasPresent)
+	}
+	return nil
+}
+
+// checkBootstrap returns an error if options tagged with bootstrap appear
+// anywhere except among the options of the top-level command
+func checkBootstrap(cmd *command, topLevel bool) error {
+	for _, spec := range cmd.specs {
+		switch {
+		case !spec.bootstrap:
+		case spec.positional:
+			return fmt.Errorf("%s: bootstrap arguments must be options, not positionals", spec.dest)
+		case !topLevel:
+			return fmt.Errorf("%s: bootstrap options must belong to the top-level command", spec.dest)
+		}
+	}
+	for _, subcmd := range cmd.subcommands {
+		if err := checkBootstrap(subcmd, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
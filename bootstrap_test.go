@@ -0,0 +1,134 @@
+package arg
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWithBootstrap(t *testing.T) {
+	type pushCmd struct {
+		Remote string `arg:"positional"`
+	}
+	var args struct {
+		Profile string   `arg:"bootstrap,env"`
+		Plugins []string `arg:"bootstrap"`
+		Region  string
+		Push    *pushCmd `arg:"subcommand"`
+	}
+	lookup := func(name string) (string, bool) {
+		return "staging", name == "PROFILE"
+	}
+	p, err := NewParser(Config{LookupEnv: lookup}, &args)
+	require.NoError(t, err)
+
+	var profile string
+	var plugins []string
+	err = p.ParseWithBootstrap([]string{"--plugins", "a", "b", "--unknown", "push", "origin"}, func() error {
+		profile = args.Profile
+		plugins = append(plugins, args.Plugins...)
+		assert.Equal(t, "", args.Region)
+		assert.Nil(t, args.Push)
+
+		// the callback can set defaults for the second pass
+		if args.Profile == "staging" {
+			args.Region = "us-east-1"
+		}
+		return nil
+	})
+	assert.Equal(t, "staging", profile)
+	assert.Equal(t, []string{"a", "b"}, plugins)
+
+	// the second pass reports the unknown option
+	assert.EqualError(t, err, "unknown argument --unknown")
+
+	err = p.ParseWithBootstrap([]string{"--profile=prod", "push", "origin"}, func() error {
+		assert.Equal(t, "prod", args.Profile)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", args.Region)
+	require.NotNil(t, args.Push)
+	assert.Equal(t, "origin", args.Push.Remote)
+}
+
+func TestParseWithBootstrapLists(t *testing.T) {
+	var args struct {
+		Plugins []string `arg:"--plugin,separate,bootstrap"`
+		Tags    []string `arg:"env,bootstrap"`
+	}
+	args.Tags = []string{"default"}
+	p, err := NewParser(Config{
+		LookupEnv: func(name string) (string, bool) {
+			if name == "TAGS" {
+				return "a,b", true
+			}
+			return "", false
+		},
+	}, &args)
+	require.NoError(t, err)
+
+	var seen []string
+	err = p.ParseWithBootstrap([]string{"--plugin", "a", "--plugin", "b"}, func() error {
+		seen = append([]string{}, args.Plugins...)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, seen)
+	assert.Equal(t, []string{"a", "b"}, args.Plugins)
+	assert.Equal(t, []string{"a", "b"}, args.Tags)
+}
+
+func TestParseWithBootstrapErrors(t *testing.T) {
+	var args struct {
+		Workers int `arg:"bootstrap"`
+	}
+	p, err := NewParser(Config{}, &args)
+	require.NoError(t, err)
+
+	err = p.ParseWithBootstrap([]string{"--workers"}, func() error {
+		t.Error("bootstrap should not be called")
+		return nil
+	})
+	assert.EqualError(t, err, "missing value for --workers")
+
+	err = p.ParseWithBootstrap([]string{"--workers", "4"}, func() error {
+		return errors.New("plugin not found")
+	})
+	assert.EqualError(t, err, "plugin not found")
+}
+
+func TestBootstrapOnlyTopLevel(t *testing.T) {
+	var args struct {
+		Push *struct {
+			Remote string `arg:"bootstrap"`
+		} `arg:"subcommand"`
+	}
+	_, err := NewParser(Config{}, &args)
+	assert.Error(t, err)
+
+	var positional struct {
+		Remote string `arg:"positional,bootstrap"`
+	}
+	_, err = NewParser(Config{}, &positional)
+	assert.Error(t, err)
+}
+
+func TestParseWithBootstrapWarnsOnce(t *testing.T) {
+	var args struct {
+		Profile string `arg:"bootstrap,env" deprecated:"use --env instead"`
+	}
+	lookup := func(name string) (string, bool) {
+		return "staging", name == "PROFILE"
+	}
+	p, stderr, _ := newRunParser(t, &args, Config{LookupEnv: lookup})
+	err := p.ParseWithBootstrap(nil, func() error {
+		assert.Empty(t, stderr.String())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "warning: environment variable PROFILE is deprecated: use --env instead\n", stderr.String())
+	assert.Len(t, p.Warnings(), 1)
+}
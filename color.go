@@ -0,0 +1,81 @@
+package arg
+
+import (
+	"io"
+	"os"
+)
+
+// ColorMode controls whether help and error messages are styled with ANSI
+// escape sequences
+type ColorMode int
+
+const (
+	// ColorAuto enables colors when writing to a terminal, unless the NO_COLOR
+	// environment variable is set to a non-empty value
+	ColorAuto ColorMode = iota
+	// ColorAlways enables colors regardless of where output is written
+	ColorAlways
+	// ColorNever disables colors
+	ColorNever
+)
+
+// ANSI escape sequences for each kind of styled text
+const (
+	ansiReset       = "\x1b[0m"
+	ansiHeader      = "\x1b[1m"
+	ansiName        = "\x1b[36m"
+	ansiPlaceholder = "\x1b[4m"
+	ansiError       = "\x1b[1;31m"
+)
+
+// style applies ANSI colors to parts of the help text, or does nothing if
+// colors are disabled
+type style struct {
+	color bool
+}
+
+// styleFor decides whether to use colors for output written to w
+func (p *Parser) styleFor(w io.Writer) style {
+	switch p.config.Color {
+	case ColorAlways:
+		return style{color: true}
+	case ColorNever:
+		return style{}
+	}
+	if noColor, _ := p.lookupEnv("NO_COLOR"); noColor != "" {
+		return style{}
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return style{}
+	}
+	_, isTerminal := terminalSize(f)
+	return style{color: isTerminal}
+}
+
+func (s style) apply(code, text string) string {
+	if !s.color || text == "" {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+// header styles section headers such as "Options:"
+func (s style) header(text string) string {
+	return s.apply(ansiHeader, text)
+}
+
+// name styles option and subcommand names
+func (s style) name(text string) string {
+	return s.apply(ansiName, text)
+}
+
+// placeholder styles the names of values, such as "N" in "--workers N"
+func (s style) placeholder(text string) string {
+	return s.apply(ansiPlaceholder, text)
+}
+
+// err styles the "error:" prefix
+func (s style) err(text string) string {
+	return s.apply(ansiError, text)
+}
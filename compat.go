@@ -0,0 +1,160 @@
+package arg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Incompatibility describes a difference between two versions of a command
+// tree that may break programs or scripts written against the older version
+type Incompatibility struct {
+	Command string // full path of the affected command, e.g. "example push"
+	Option  string // long name of the affected option, or empty for command changes
+	Message string // description of the change
+}
+
+// String formats the incompatibility for display
+func (c Incompatibility) String() string {
+	if c.Option == "" {
+		return fmt.Sprintf("%s: %s", c.Command, c.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", c.Command, c.Option, c.Message)
+}
+
+// CheckCompatibility compares two versions of a command tree and reports the
+// changes that would break existing users of the old version: removed options
+// and subcommands, options that became required, changed short names and
+// environment variables, changed types, and positionals that moved. Options
+// are matched by name and positionals by position, so renaming a positional
+// is not reported. Snapshots can be obtained from a
+// live parser with Parser.Spec or loaded with ReadSpecJSON. The result is
+// empty if the new version is compatible with the old one.
+func CheckCompatibility(old, new *CommandSpec) []Incompatibility {
+	return checkCommandCompatibility(nil, old, new)
+}
+
+// checkCommandCompatibility compares two versions of a single command and,
+// recursively, its subcommands
+func checkCommandCompatibility(ancestors []string, old, new *CommandSpec) []Incompatibility {
+	// the top-level command is identified by position, not name, so that
+	// renaming the program binary is not reported
+	names := append(append([]string{}, ancestors...), new.Name)
+	path := strings.Join(names, " ")
+
+	var out []Incompatibility
+	report := func(option, format string, args ...interface{}) {
+		out = append(out, Incompatibility{
+			Command: path,
+			Option:  option,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	// options are matched by name, and positionals by position, since the
+	// names of positionals do not appear on the command line
+	oldOptions, oldPositionals := splitOptionSpecs(old.Options)
+	newOptions, newPositionals := splitOptionSpecs(new.Options)
+	byName := func(specs []*OptionSpec) map[string]int {
+		m := make(map[string]int)
+		for i, spec := range specs {
+			m[spec.Long] = i
+		}
+		return m
+	}
+	oldOptionIndex, newOptionIndex := byName(oldOptions), byName(newOptions)
+	newPositionalIndex := byName(newPositionals)
+
+	for _, o := range oldOptions {
+		i, found := newOptionIndex[o.Long]
+		if !found {
+			if _, found := newPositionalIndex[o.Long]; found {
+				report(o.Long, "changed from an option to a positional argument")
+			} else {
+				report(o.Long, "removed")
+			}
+			continue
+		}
+		n := newOptions[i]
+		if n.Required && !o.Required {
+			report(o.Long, "is now required")
+		}
+		if o.Short != n.Short && o.Short != "" {
+			if n.Short == "" {
+				report(o.Long, "short name -%s removed", o.Short)
+			} else {
+				report(o.Long, "short name changed from -%s to -%s", o.Short, n.Short)
+			}
+		}
+		// the environment variable of a secret option is not exported
+		if o.Env != n.Env && o.Env != "" && !n.Secret {
+			if n.Env == "" {
+				report(o.Long, "environment variable %s removed", o.Env)
+			} else {
+				report(o.Long, "environment variable changed from %s to %s", o.Env, n.Env)
+			}
+		}
+		if o.Type != n.Type {
+			report(o.Long, "type changed from %s to %s", o.Type, n.Type)
+		}
+	}
+
+	for _, n := range newOptions {
+		if _, found := oldOptionIndex[n.Long]; !found && n.Required {
+			report(n.Long, "added as a required option")
+		}
+	}
+
+	for i, o := range oldPositionals {
+		if j, found := newPositionalIndex[o.Long]; found && j != i {
+			report(o.Long, "moved from position %d to %d", i+1, j+1)
+		}
+		if i >= len(newPositionals) {
+			if _, found := newOptionIndex[o.Long]; found {
+				report(o.Long, "changed from a positional argument to an option")
+			} else {
+				report(o.Long, "removed")
+			}
+			continue
+		}
+		n := newPositionals[i]
+		if n.Required && !o.Required {
+			report(o.Long, "is now required")
+		}
+		if o.Type != n.Type {
+			report(o.Long, "type changed from %s to %s", o.Type, n.Type)
+		}
+	}
+
+	for i := len(oldPositionals); i < len(newPositionals); i++ {
+		if n := newPositionals[i]; n.Required {
+			report(n.Long, "added as a required positional argument")
+		}
+	}
+
+	newSubcommands := make(map[string]*CommandSpec)
+	for _, subcmd := range new.Subcommands {
+		newSubcommands[subcmd.Name] = subcmd
+	}
+	for _, o := range old.Subcommands {
+		n, found := newSubcommands[o.Name]
+		if !found {
+			report("", "subcommand %s removed", o.Name)
+			continue
+		}
+		out = append(out, checkCommandCompatibility(names, o, n)...)
+	}
+
+	return out
+}
+
+// splitOptionSpecs separates options from positionals, keeping their order
+func splitOptionSpecs(specs []*OptionSpec) (options, positionals []*OptionSpec) {
+	for _, spec := range specs {
+		if spec.Positional {
+			positionals = append(positionals, spec)
+		} else {
+			options = append(options, spec)
+		}
+	}
+	return options, positionals
+}
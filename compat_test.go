@@ -0,0 +1,92 @@
+package arg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCompatibilityIdentical(t *testing.T) {
+	var args struct {
+		Workers int `arg:"-w,env"`
+	}
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+	assert.Empty(t, CheckCompatibility(p.Spec(), p.Spec()))
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	type oldPush struct {
+		Force  bool
+		Remote string
+	}
+	var oldArgs struct {
+		Workers int    `arg:"-w,env"`
+		Name    string `arg:"-n"`
+		Output  string
+		Push    *oldPush  `arg:"subcommand"`
+		Pull    *struct{} `arg:"subcommand"`
+	}
+
+	type newPush struct {
+		Remote string `arg:"required"`
+	}
+	var newArgs struct {
+		Workers string `arg:"-j,env:NUM_WORKERS"`
+		Name    string
+		Output  string
+		Level   int      `arg:"required"`
+		Push    *newPush `arg:"subcommand"`
+	}
+
+	oldParser, err := NewParser(Config{Program: "old"}, &oldArgs)
+	require.NoError(t, err)
+	newParser, err := NewParser(Config{Program: "example"}, &newArgs)
+	require.NoError(t, err)
+
+	var messages []string
+	for _, c := range CheckCompatibility(oldParser.Spec(), newParser.Spec()) {
+		messages = append(messages, c.String())
+	}
+	assert.Equal(t, []string{
+		"example: workers: short name changed from -w to -j",
+		"example: workers: environment variable changed from WORKERS to NUM_WORKERS",
+		"example: workers: type changed from int to string",
+		"example: name: short name -n removed",
+		"example: level: added as a required option",
+		"example push: force: removed",
+		"example push: remote: is now required",
+		"example: subcommand pull removed",
+	}, messages)
+}
+
+func TestCheckCompatibilityPositionals(t *testing.T) {
+	var oldArgs struct {
+		Src  string `arg:"positional"`
+		Dst  string `arg:"positional"`
+		Mode string `arg:"positional"`
+	}
+	var newArgs struct {
+		Dst    string `arg:"positional"`
+		Src    string `arg:"positional"`
+		Method int    `arg:"positional"`
+		Extra  string `arg:"positional,required"`
+	}
+
+	oldParser, err := NewParser(Config{Program: "example"}, &oldArgs)
+	require.NoError(t, err)
+	newParser, err := NewParser(Config{Program: "example"}, &newArgs)
+	require.NoError(t, err)
+
+	var messages []string
+	for _, c := range CheckCompatibility(oldParser.Spec(), newParser.Spec()) {
+		messages = append(messages, c.String())
+	}
+	assert.Equal(t, []string{
+		"example: src: moved from position 1 to 2",
+		"example: dst: moved from position 2 to 1",
+		"example: mode: type changed from string to int",
+		"example: extra: added as a required positional argument",
+	}, messages)
+}
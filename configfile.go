@@ -0,0 +1,353 @@
+package arg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// ConfigEntry is a key in a configuration file, holding either the value of
+// an option or a section with the options of a subcommand
+type ConfigEntry struct {
+	Key     string        // long name of an option, or name of a subcommand
+	Line    int           // line number of the key, for error messages
+	Values  []string      // value of the option, or one value per element of a list
+	List    bool          // true if the value was a list
+	Section []ConfigEntry // entries of a subcommand section, or nil for options
+}
+
+// ConfigDecoder is the interface for reading configuration files. JSON is
+// supported without a decoder; decoders for other formats are registered by
+// file extension in Config.ConfigDecoders.
+type ConfigDecoder interface {
+	// DecodeConfig parses the contents of a configuration file. Syntax errors
+	// should mention the line on which they occur.
+	DecodeConfig(data []byte) ([]ConfigEntry, error)
+}
+
+// ConfigDecoderFunc adapts a function to the ConfigDecoder interface
+type ConfigDecoderFunc func(data []byte) ([]ConfigEntry, error)
+
+// DecodeConfig calls the function
+func (f ConfigDecoderFunc) DecodeConfig(data []byte) ([]ConfigEntry, error) {
+	return f(data)
+}
+
+// configValue is the value of an option and the place it was read from
+type configValue struct {
+	file  string
+	key   string // the key, including the names of enclosing sections
+	entry ConfigEntry
+}
+
+// captureConfigFiles sets options that were not given on the command line or
+// in the environment from the configuration files. It covers the given command
+// and its ancestors.
+func (p *Parser) captureConfigFiles(cmd *command, wasPresent map[*spec]bool) error {
+	if p.ignoreFiles {
+		return nil
+	}
+
+	var chain []*command
+	active := make(map[*command]bool)
+	for c := cmd; c != nil; c = c.parent {
+		chain = append([]*command{c}, chain...)
+		active[c] = true
+	}
+
+	// files from the config take effect first, so that files named on the
+	// command line override them
+	type configFile struct {
+		path     string
+		optional bool
+	}
+	var files []configFile
+	for _, path := range p.config.ConfigFiles {
+		files = append(files, configFile{path: path, optional: true})
+	}
+	for _, c := range chain {
+		for _, spec := range c.specs {
+			if !spec.configFile {
+				continue
+			}
+			for _, path := range configFilePaths(p.val(spec.dest)) {
+				files = append(files, configFile{path: path, optional: !wasPresent[spec]})
+			}
+		}
+	}
+
+	// later files override earlier ones
+	values := make(map[*spec]configValue)
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			if f.optional && os.IsNotExist(err) {
+				continue
+			}
+			return p.errorf(MsgConfigFileError, f.path, err)
+		}
+		entries, err := p.configDecoder(f.path).DecodeConfig(data)
+		if syntaxErr, ok := err.(*configError); ok {
+			return p.errorf(MsgConfigFileLine, f.path, syntaxErr.line, p.configErrorText(syntaxErr))
+		}
+		if err != nil {
+			return p.errorf(MsgConfigFileError, f.path, err)
+		}
+		if err := p.collectConfig(f.path, nil, p.cmd, entries, active, values); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range chain {
+		for _, spec := range c.specs {
+			v, found := values[spec]
+			if !found || wasPresent[spec] {
+				continue
+			}
+			if err := p.setFromConfig(spec, v); err != nil {
+				return err
+			}
+			wasPresent[spec] = true
+			if spec.deprecated != "" {
+				p.warn(p.msg(MsgDeprecatedOption, v.key, spec.deprecated))
+			}
+		}
+	}
+	return nil
+}
+
+// collectConfig records the values in a section of a configuration file for
+// the options of the given command, checking that every key is known
+func (p *Parser) collectConfig(file string, section []string, cmd *command, entries []ConfigEntry, active map[*command]bool, values map[*spec]configValue) error {
+	for _, entry := range entries {
+		path := append(append([]string{}, section...), entry.Key)
+		key := strings.Join(path, ".")
+
+		if subcmd := findSubcommand(cmd.subcommands, entry.Key); subcmd != nil && entry.Section != nil {
+			err := p.collectConfig(file, path, subcmd, entry.Section, active, values)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		var found *spec
+		for _, spec := range cmd.specs {
+			if spec.long == entry.Key {
+				found = spec
+			}
+		}
+		if found == nil {
+			return p.errorf(MsgUnknownConfigKey, file, entry.Line, key)
+		}
+		if entry.Section != nil {
+			return p.errorf(MsgInvalidConfigValue, file, entry.Line, key, p.msg(MsgConfigSection))
+		}
+		if active[cmd] {
+			values[found] = configValue{file: file, key: key, entry: entry}
+		}
+	}
+	return nil
+}
+
+// setFromConfig stores a value from a configuration file in an option
+func (p *Parser) setFromConfig(spec *spec, v configValue) error {
+	var err error
+	switch {
+	case spec.multiple:
+		err = setSlice(p.val(spec.dest), v.entry.Values, true)
+	case v.entry.List || len(v.entry.Values) != 1:
+		err = errors.New(p.msg(MsgConfigList))
+	default:
+		err = parseValue(p.val(spec.dest), v.entry.Values[0])
+	}
+	if err != nil {
+		return p.errorf(MsgInvalidConfigValue, v.file, v.entry.Line, v.key, err)
+	}
+	return nil
+}
+
+// configDecoder gets the decoder for a file based on its extension
+func (p *Parser) configDecoder(path string) ConfigDecoder {
+	if dec, ok := p.config.ConfigDecoders[filepath.Ext(path)]; ok {
+		return dec
+	}
+	return ConfigDecoderFunc(decodeJSONConfig)
+}
+
+// configFilePaths gets the paths in the value of an option tagged configfile
+func configFilePaths(v reflect.Value) []string {
+	var paths []string
+	switch v.Kind() {
+	case reflect.String:
+		if v.String() != "" {
+			paths = append(paths, v.String())
+		}
+	case reflect.Ptr:
+		if !v.IsNil() {
+			paths = configFilePaths(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			paths = append(paths, configFilePaths(v.Index(i))...)
+		}
+	}
+	return paths
+}
+
+// configError is a syntax error in a configuration file in JSON format. It
+// holds either a message or an error from the JSON decoder.
+type configError struct {
+	line int
+	id   MessageID
+	args []interface{}
+	err  error
+}
+
+// Error returns the English text of the error
+func (e *configError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("line %d: %v", e.line, e.err)
+	}
+	return fmt.Sprintf("line %d: %s", e.line, fmt.Sprintf(englishMessages[e.id], e.args...))
+}
+
+// configErrorText describes a syntax error without its line number, in the
+// configured language
+func (p *Parser) configErrorText(e *configError) string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return p.msg(e.id, e.args...)
+}
+
+// decodeJSONConfig reads a configuration file in JSON format, which must hold
+// a single object. Syntax errors are of type *configError.
+func decodeJSONConfig(data []byte) ([]ConfigEntry, error) {
+	r := jsonConfigReader{dec: json.NewDecoder(bytes.NewReader(data)), data: data}
+	r.dec.UseNumber()
+
+	tok, err := r.dec.Token()
+	if err != nil {
+		return nil, r.wrap(err)
+	}
+	if tok != json.Delim('{') {
+		return nil, &configError{line: r.line(), id: MsgConfigExpectedObject}
+	}
+	entries, err := r.object()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.dec.Token(); err != io.EOF {
+		return nil, &configError{line: r.line(), id: MsgConfigTrailingData}
+	}
+	return entries, nil
+}
+
+// jsonConfigReader reads JSON tokens while keeping track of line numbers
+type jsonConfigReader struct {
+	dec  *json.Decoder
+	data []byte
+}
+
+// line gets the line number of the current position
+func (r *jsonConfigReader) line() int {
+	return r.lineAt(r.dec.InputOffset())
+}
+
+func (r *jsonConfigReader) lineAt(offset int64) int {
+	if offset > int64(len(r.data)) {
+		offset = int64(len(r.data))
+	}
+	return 1 + bytes.Count(r.data[:offset], []byte("\n"))
+}
+
+// wrap adds the line number to an error from the decoder
+func (r *jsonConfigReader) wrap(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return &configError{line: r.line(), id: MsgConfigEndOfFile}
+	}
+	if syntaxErr, ok := err.(*json.SyntaxError); ok {
+		if syntaxErr.Offset >= int64(len(r.data)) {
+			return &configError{line: r.lineAt(syntaxErr.Offset), id: MsgConfigEndOfFile}
+		}
+		return &configError{line: r.lineAt(syntaxErr.Offset), err: err}
+	}
+	return &configError{line: r.line(), err: err}
+}
+
+// object reads the members of an object, after its opening brace
+func (r *jsonConfigReader) object() ([]ConfigEntry, error) {
+	entries := []ConfigEntry{}
+	for r.dec.More() {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return nil, r.wrap(err)
+		}
+		entry := ConfigEntry{Key: tok.(string), Line: r.line()}
+
+		tok, err = r.dec.Token()
+		if err != nil {
+			return nil, r.wrap(err)
+		}
+		switch tok {
+		case nil:
+			continue // null leaves the option unset
+		case json.Delim('{'):
+			if entry.Section, err = r.object(); err != nil {
+				return nil, err
+			}
+		case json.Delim('['):
+			entry.List = true
+			if entry.Values, err = r.array(); err != nil {
+				return nil, err
+			}
+		default:
+			entry.Values = []string{jsonScalar(tok)}
+		}
+		entries = append(entries, entry)
+	}
+	if _, err := r.dec.Token(); err != nil {
+		return nil, r.wrap(err)
+	}
+	return entries, nil
+}
+
+// array reads the elements of a list of scalars, after its opening bracket
+func (r *jsonConfigReader) array() ([]string, error) {
+	values := []string{}
+	for r.dec.More() {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return nil, r.wrap(err)
+		}
+		if _, isDelim := tok.(json.Delim); isDelim || tok == nil {
+			return nil, &configError{line: r.line(), id: MsgConfigListElement}
+		}
+		values = append(values, jsonScalar(tok))
+	}
+	if _, err := r.dec.Token(); err != nil {
+		return nil, r.wrap(err)
+	}
+	return values, nil
+}
+
+// jsonScalar formats a string, number or boolean token as it would appear on
+// the command line
+func jsonScalar(tok json.Token) string {
+	switch v := tok.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
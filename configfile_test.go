@@ -0,0 +1,315 @@
+package arg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfigFile writes a file to a temporary directory and returns its path
+func writeConfigFile(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "go-arg-config")
+	require.NoError(t, err)
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+type upperName string
+
+func (n *upperName) UnmarshalArg(b []byte) error {
+	*n = upperName(strings.ToUpper(string(b)))
+	return nil
+}
+
+func TestConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "app.json", `{
+  "workers": 4,
+  "verbose": true,
+  "tags": ["a", "b"],
+  "owner": "alice",
+  "ratio": 0.5
+}`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var args struct {
+		Workers int
+		Verbose bool
+		Tags    []string
+		Owner   upperName
+		Ratio   float64
+	}
+	p, err := NewParser(Config{ConfigFiles: []string{path}}, &args)
+	require.NoError(t, err)
+	require.NoError(t, p.Parse(nil))
+	assert.Equal(t, 4, args.Workers)
+	assert.True(t, args.Verbose)
+	assert.Equal(t, []string{"a", "b"}, args.Tags)
+	assert.Equal(t, upperName("ALICE"), args.Owner)
+	assert.Equal(t, 0.5, args.Ratio)
+}
+
+func TestConfigFilePrecedence(t *testing.T) {
+	path := writeConfigFile(t, "app.json", `{"a": "file", "b": "file", "c": "file"}`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	lookup := func(name string) (string, bool) {
+		if name == "B" || name == "C" {
+			return "env", true
+		}
+		return "", false
+	}
+	var args struct {
+		A string `arg:"env"`
+		B string `arg:"env"`
+		C string `arg:"env"`
+		D string
+	}
+	args.D = "default"
+	p, err := NewParser(Config{ConfigFiles: []string{path}, LookupEnv: lookup}, &args)
+	require.NoError(t, err)
+	require.NoError(t, p.Parse([]string{"--c", "cmdline"}))
+	assert.Equal(t, "file", args.A)
+	assert.Equal(t, "env", args.B)
+	assert.Equal(t, "cmdline", args.C)
+	assert.Equal(t, "default", args.D)
+}
+
+func TestConfigFileSatisfiesRequired(t *testing.T) {
+	path := writeConfigFile(t, "app.json", `{"workers": 4}`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var args struct {
+		Workers int `arg:"required"`
+	}
+	p, err := NewParser(Config{ConfigFiles: []string{path}}, &args)
+	require.NoError(t, err)
+	require.NoError(t, p.Parse(nil))
+	assert.Equal(t, 4, args.Workers)
+}
+
+func TestConfigFileSubcommandSections(t *testing.T) {
+	path := writeConfigFile(t, "app.json", `{
+  "quiet": true,
+  "push": {"remote": "origin"},
+  "pull": {"rebase": true}
+}`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	type pushCmd struct {
+		Remote string
+	}
+	type pullCmd struct {
+		Rebase bool
+	}
+	var args struct {
+		Quiet bool
+		Push  *pushCmd `arg:"subcommand"`
+		Pull  *pullCmd `arg:"subcommand"`
+	}
+	p, err := NewParser(Config{ConfigFiles: []string{path}}, &args)
+	require.NoError(t, err)
+	require.NoError(t, p.Parse([]string{"push"}))
+	assert.True(t, args.Quiet)
+	require.NotNil(t, args.Push)
+	assert.Equal(t, "origin", args.Push.Remote)
+	assert.Nil(t, args.Pull)
+}
+
+func TestConfigFileUnknownKey(t *testing.T) {
+	path := writeConfigFile(t, "app.json", `{
+  "workers": 4,
+  "push": {
+    "remote": "origin",
+    "forse": true
+  }
+}`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	type pushCmd struct {
+		Remote string
+		Force  bool
+	}
+	var args struct {
+		Workers int
+		Push    *pushCmd `arg:"subcommand"`
+	}
+	p, err := NewParser(Config{ConfigFiles: []string{path}}, &args)
+	require.NoError(t, err)
+
+	// unknown keys are reported even for subcommands that were not selected
+	err = p.Parse(nil)
+	assert.EqualError(t, err, path+":5: unknown key push.forse")
+}
+
+func TestConfigFileInvalidValue(t *testing.T) {
+	path := writeConfigFile(t, "app.json", "{\n  \"workers\": \"many\"\n}")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var args struct {
+		Workers int
+	}
+	p, err := NewParser(Config{ConfigFiles: []string{path}}, &args)
+	require.NoError(t, err)
+	err = p.Parse(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), path+":2: error processing workers: ")
+}
+
+func TestConfigFileDeprecatedOption(t *testing.T) {
+	path := writeConfigFile(t, "app.json", `{"out": "x", "push": {"force": true}}`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var args struct {
+		Out  string `deprecated:"use output instead"`
+		Push *struct {
+			Force bool `deprecated:"use --mode=force instead"`
+		} `arg:"subcommand"`
+	}
+	p, err := NewParser(Config{ConfigFiles: []string{path}, Stderr: ioutil.Discard}, &args)
+	require.NoError(t, err)
+	require.NoError(t, p.Parse([]string{"push"}))
+	assert.Equal(t, "x", args.Out)
+	assert.Equal(t, []string{
+		"out is deprecated: use output instead",
+		"push.force is deprecated: use --mode=force instead",
+	}, p.Warnings())
+}
+
+func TestConfigFileSyntaxError(t *testing.T) {
+	path := writeConfigFile(t, "app.json", "{\n  \"workers\": 4,\n  \"verbose\" true\n}")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var args struct {
+		Workers int
+		Verbose bool
+	}
+	p, err := NewParser(Config{ConfigFiles: []string{path}}, &args)
+	require.NoError(t, err)
+	err = p.Parse(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), path+":3: ")
+}
+
+func TestConfigFileStructureErrors(t *testing.T) {
+	cases := []struct {
+		content  string
+		expected string
+	}{
+		{"[1, 2]", ":1: expected an object"},
+		{"{\n  \"workers\": 4\n}\n{}", ":4: unexpected data after the object"},
+		{"{\n  \"workers\": 4,\n", ":3: unexpected end of file"},
+		{"{\n  \"tags\": [\"a\", {}]\n}", ":2: lists may only contain strings, numbers and booleans"},
+		{"{\n  \"workers\": {\"n\": 4}\n}", ":2: error processing workers: expected a value, not a section"},
+		{"{\n  \"workers\": [4, 5]\n}", ":2: error processing workers: expected a single value, not a list"},
+	}
+	for _, c := range cases {
+		path := writeConfigFile(t, "app.json", c.content)
+		defer os.RemoveAll(filepath.Dir(path))
+
+		var args struct {
+			Workers int
+			Tags    []string
+		}
+		p, err := NewParser(Config{ConfigFiles: []string{path}}, &args)
+		require.NoError(t, err)
+		err = p.Parse(nil)
+		assert.EqualError(t, err, path+c.expected, c.content)
+	}
+}
+
+func TestConfigFileTranslatedSyntaxError(t *testing.T) {
+	path := writeConfigFile(t, "app.json", "{\n  \"workers\": [4, 5]\n}")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var args struct {
+		Workers int
+	}
+	p, err := NewParser(Config{
+		ConfigFiles: []string{path},
+		Messages:    MessageMap{MsgConfigList: "Liste nicht erlaubt", MsgConfigEndOfFile: "unerwartetes Dateiende"},
+	}, &args)
+	require.NoError(t, err)
+	err = p.Parse(nil)
+	assert.EqualError(t, err, path+":2: error processing workers: Liste nicht erlaubt")
+
+	path = writeConfigFile(t, "app.json", "{")
+	defer os.RemoveAll(filepath.Dir(path))
+	p.config.ConfigFiles = []string{path}
+	err = p.Parse(nil)
+	assert.EqualError(t, err, path+":1: unerwartetes Dateiende")
+}
+
+func TestConfigFileOption(t *testing.T) {
+	path := writeConfigFile(t, "app.json", `{"workers": 4}`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var args struct {
+		Config  string `arg:"configfile"`
+		Workers int
+	}
+	args.Config = filepath.Join(filepath.Dir(path), "missing.json")
+	p, err := NewParser(Config{}, &args)
+	require.NoError(t, err)
+
+	// a missing default file is ignored
+	require.NoError(t, p.Parse(nil))
+	assert.Equal(t, 0, args.Workers)
+
+	require.NoError(t, p.Parse([]string{"--config", path}))
+	assert.Equal(t, 4, args.Workers)
+
+	// a missing file given on the command line is an error
+	err = p.Parse([]string{"--config", path + ".missing"})
+	assert.Error(t, err)
+}
+
+func TestConfigFileOverride(t *testing.T) {
+	first := writeConfigFile(t, "first.json", `{"a": "first", "b": "first"}`)
+	defer os.RemoveAll(filepath.Dir(first))
+	second := writeConfigFile(t, "second.json", `{"b": "second"}`)
+	defer os.RemoveAll(filepath.Dir(second))
+
+	var args struct {
+		Config []string `arg:"configfile"`
+		A      string
+		B      string
+	}
+	p, err := NewParser(Config{ConfigFiles: []string{first}}, &args)
+	require.NoError(t, err)
+	require.NoError(t, p.Parse([]string{"--config", second}))
+	assert.Equal(t, "first", args.A)
+	assert.Equal(t, "second", args.B)
+}
+
+func TestConfigFileCustomDecoder(t *testing.T) {
+	path := writeConfigFile(t, "app.conf", "workers 4\nowner bob\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	decoder := ConfigDecoderFunc(func(data []byte) ([]ConfigEntry, error) {
+		var entries []ConfigEntry
+		for i, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			fields := strings.Fields(line)
+			entries = append(entries, ConfigEntry{Key: fields[0], Line: i + 1, Values: fields[1:]})
+		}
+		return entries, nil
+	})
+
+	var args struct {
+		Workers int
+		Owner   string
+	}
+	p, err := NewParser(Config{
+		ConfigFiles:    []string{path},
+		ConfigDecoders: map[string]ConfigDecoder{".conf": decoder},
+	}, &args)
+	require.NoError(t, err)
+	require.NoError(t, p.Parse(nil))
+	assert.Equal(t, 4, args.Workers)
+	assert.Equal(t, "bob", args.Owner)
+}
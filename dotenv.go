@@ -0,0 +1,169 @@
+package arg
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// envFileVar is a variable from a dotenv file and the place it was read from
+type envFileVar struct {
+	value string
+	file  string
+	line  int
+}
+
+// loadEnvFiles reads the files in Config.EnvFiles so that their variables can
+// be used when they are not in the environment
+func (p *Parser) loadEnvFiles() error {
+	p.envFileVars = nil
+	if p.config.IgnoreEnv {
+		return nil
+	}
+	for _, path := range p.config.EnvFiles {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return p.errorf(MsgEnvFileError, path, err)
+		}
+		vars, err := parseDotenv(string(data))
+		if err != nil {
+			syntaxErr := err.(*dotenvError)
+			return p.errorf(MsgEnvFileLine, path, syntaxErr.line, p.msg(syntaxErr.id, syntaxErr.args...))
+		}
+		if p.envFileVars == nil {
+			p.envFileVars = make(map[string]envFileVar)
+		}
+		for name, v := range vars {
+			v.file = path
+			p.envFileVars[name] = v
+		}
+	}
+	return nil
+}
+
+// envFileError adds the file and line to an error about an environment
+// variable whose value came from a dotenv file
+func (p *Parser) envFileError(name string, err error) error {
+	if _, found := p.lookupRealEnv(name); found {
+		return err
+	}
+	if v, found := p.envFileVars[name]; found {
+		return p.errorf(MsgEnvFileLine, v.file, v.line, err)
+	}
+	return err
+}
+
+// dotenvError is a syntax error in a dotenv file
+type dotenvError struct {
+	line int
+	id   MessageID
+	args []interface{}
+}
+
+// Error returns the English text of the error
+func (e *dotenvError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, fmt.Sprintf(englishMessages[e.id], e.args...))
+}
+
+// parseDotenv parses the contents of a dotenv file. Each line holds a
+// KEY=VALUE assignment, optionally preceded by "export". Values may be
+// single-quoted, which preserves them exactly, or double-quoted, which
+// interprets backslash escapes. Unquoted values end at a " #" comment.
+// Errors are of type *dotenvError.
+func parseDotenv(data string) (map[string]envFileVar, error) {
+	vars := make(map[string]envFileVar)
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") {
+			line = strings.TrimSpace(line[len("export "):])
+		}
+
+		pos := strings.Index(line, "=")
+		if pos == -1 {
+			return nil, &dotenvError{line: i + 1, id: MsgEnvFileAssignment}
+		}
+		name := strings.TrimSpace(line[:pos])
+		if !isEnvName(name) {
+			return nil, &dotenvError{line: i + 1, id: MsgEnvFileName, args: []interface{}{name}}
+		}
+
+		value, id := dotenvValue(strings.TrimSpace(line[pos+1:]))
+		if id != 0 {
+			return nil, &dotenvError{line: i + 1, id: id}
+		}
+		vars[name] = envFileVar{value: value, line: i + 1}
+	}
+	return vars, nil
+}
+
+// dotenvValue interprets the value part of a dotenv assignment. If the value
+// is malformed, it returns the ID of the message describing the problem.
+func dotenvValue(s string) (string, MessageID) {
+	switch {
+	case strings.HasPrefix(s, "'"):
+		end := strings.Index(s[1:], "'")
+		if end == -1 {
+			return "", MsgEnvFileQuote
+		}
+		if rest := strings.TrimSpace(s[end+2:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", MsgEnvFileTrailing
+		}
+		return s[1 : end+1], 0
+
+	case strings.HasPrefix(s, `"`):
+		var out strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == '"':
+				if rest := strings.TrimSpace(s[i+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+					return "", MsgEnvFileTrailing
+				}
+				return out.String(), 0
+			case c == '\\' && i+1 < len(s):
+				i++
+				switch s[i] {
+				case 'n':
+					out.WriteByte('\n')
+				case 't':
+					out.WriteByte('\t')
+				case 'r':
+					out.WriteByte('\r')
+				default:
+					out.WriteByte(s[i])
+				}
+			default:
+				out.WriteByte(c)
+			}
+		}
+		return "", MsgEnvFileQuote
+
+	default:
+		if pos := strings.Index(s, " #"); pos != -1 {
+			s = s[:pos]
+		}
+		return strings.TrimSpace(s), 0
+	}
+}
+
+// isEnvName returns true if s is a valid environment variable name
+func isEnvName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
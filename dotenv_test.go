@@ -0,0 +1,139 @@
+package arg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDotenv(t *testing.T) {
+	vars, err := parseDotenv(`# comment
+PLAIN=value
+export EXPORTED=yes
+SPACED = padded value  # trailing comment
+SINGLE='it is # not a comment'
+SINGLE2='a \n b'
+DOUBLE="line1\nline2 \"quoted\""
+EMPTY=
+HASH=a#b
+`)
+	require.NoError(t, err)
+	values := make(map[string]string)
+	for name, v := range vars {
+		values[name] = v.value
+	}
+	assert.Equal(t, 7, vars["DOUBLE"].line)
+	assert.Equal(t, map[string]string{
+		"PLAIN":    "value",
+		"EXPORTED": "yes",
+		"SPACED":   "padded value",
+		"SINGLE":   "it is # not a comment",
+		"SINGLE2":  `a \n b`,
+		"DOUBLE":   "line1\nline2 \"quoted\"",
+		"EMPTY":    "",
+		"HASH":     "a#b",
+	}, values)
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	for _, test := range []struct {
+		data string
+		err  string
+	}{
+		{"A=1\nB\n", "line 2: expected KEY=VALUE"},
+		{"1A=x", `line 1: invalid variable name "1A"`},
+		{"\n\nA='x", "line 3: unterminated quote"},
+		{`A="x`, `line 1: unterminated quote`},
+		{`A="x" y`, "line 1: unexpected text after quoted value"},
+	} {
+		_, err := parseDotenv(test.data)
+		assert.EqualError(t, err, test.err, test.data)
+	}
+}
+
+func TestEnvFiles(t *testing.T) {
+	base := writeConfigFile(t, ".env", "A=base\nB=base\nC=base\n")
+	defer os.RemoveAll(filepath.Dir(base))
+	local := writeConfigFile(t, ".env.local", "export B=local\n")
+	defer os.RemoveAll(filepath.Dir(local))
+
+	var args struct {
+		A string `arg:"env"`
+		B string `arg:"env"`
+		C string `arg:"env"`
+	}
+	p, err := NewParser(Config{
+		EnvFiles: []string{base, local, "does-not-exist.env"},
+		LookupEnv: func(name string) (string, bool) {
+			if name == "C" {
+				return "real", true
+			}
+			return "", false
+		},
+	}, &args)
+	require.NoError(t, err)
+	require.NoError(t, p.Parse([]string{"-a", "flag"}))
+	assert.Equal(t, "flag", args.A)
+	assert.Equal(t, "local", args.B)
+	assert.Equal(t, "real", args.C)
+}
+
+func TestEnvFilesIgnoreEnv(t *testing.T) {
+	path := writeConfigFile(t, ".env", "A=file\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var args struct {
+		A string `arg:"env"`
+	}
+	p, err := NewParser(Config{EnvFiles: []string{path}, IgnoreEnv: true}, &args)
+	require.NoError(t, err)
+	require.NoError(t, p.Parse(nil))
+	assert.Equal(t, "", args.A)
+}
+
+func TestEnvFilesSyntaxError(t *testing.T) {
+	path := writeConfigFile(t, ".env", "A=1\nnot an assignment\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var args struct {
+		A string `arg:"env"`
+	}
+	p, err := NewParser(Config{EnvFiles: []string{path}}, &args)
+	require.NoError(t, err)
+	err = p.Parse(nil)
+	assert.EqualError(t, err, path+":2: expected KEY=VALUE")
+}
+
+func TestEnvFilesTranslatedSyntaxError(t *testing.T) {
+	path := writeConfigFile(t, ".env", "A=\"x\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var args struct {
+		A string `arg:"env"`
+	}
+	p, err := NewParser(Config{
+		EnvFiles: []string{path},
+		Messages: MessageMap{MsgEnvFileQuote: "Anführungszeichen nicht geschlossen"},
+	}, &args)
+	require.NoError(t, err)
+	err = p.Parse(nil)
+	assert.EqualError(t, err, path+":1: Anführungszeichen nicht geschlossen")
+}
+
+func TestEnvFilesInvalidValue(t *testing.T) {
+	path := writeConfigFile(t, ".env", "WORKERS=many\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var args struct {
+		Workers int `arg:"env"`
+	}
+	p, err := NewParser(Config{EnvFiles: []string{path}, LookupEnv: func(string) (string, bool) { return "", false }}, &args)
+	require.NoError(t, err)
+	err = p.Parse(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), path+":1: ")
+	assert.Contains(t, err.Error(), "WORKERS")
+}
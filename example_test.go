@@ -11,7 +11,7 @@ func split(s string) []string {
 }
 
 // This example demonstrates basic usage
-func Example() {
+func Example_basic() {
 	// These are the args you would pass in on the command line
 	os.Args = split("./example --foo=hello --bar")
 
@@ -122,7 +122,7 @@ func Example_helpText() {
 	MustParse(&args)
 
 	// output:
-	// Usage: example [--verbose] [--dataset DATASET] [--optimize OPTIMIZE] INPUT [OUTPUT [OUTPUT ...]]
+	// Usage: example [--verbose] [--dataset DATASET] [--optimize N] INPUT [OUTPUT [OUTPUT ...]]
 	//
 	// Positional arguments:
 	//   INPUT
@@ -131,8 +131,7 @@ func Example_helpText() {
 	// Options:
 	//   --verbose, -v          verbosity level
 	//   --dataset DATASET      dataset to use
-	//   --optimize OPTIMIZE, -O OPTIMIZE
-	//                          optimization level
+	//   --optimize N, -O N     optimization level
 	//   --help, -h             display this help and exit
 }
 
@@ -199,13 +198,17 @@ func Example_helpTextForSubcommand() {
 	MustParse(&args)
 
 	// output:
-	// Usage: example get ITEM
+	// fetch an item and print it
+	// Usage: example [--verbose] get ITEM
 	//
 	// Positional arguments:
 	//   ITEM                   item to fetch
 	//
 	// Options:
 	//   --help, -h             display this help and exit
+	//
+	// Global options:
+	//   --verbose
 }
 
 // This example shows the error string generated by go-arg when an invalid option is provided
@@ -228,7 +231,7 @@ func Example_errorText() {
 	MustParse(&args)
 
 	// output:
-	// Usage: example [--verbose] [--dataset DATASET] [--optimize OPTIMIZE] INPUT [OUTPUT [OUTPUT ...]]
+	// Usage: example [--verbose] [--dataset DATASET] [--optimize N] INPUT [OUTPUT [OUTPUT ...]]
 	// error: error processing --optimize: strconv.ParseInt: parsing "INVALID": invalid syntax
 }
 
@@ -252,7 +255,7 @@ func Example_errorTextForSubcommand() {
 	MustParse(&args)
 
 	// output:
-	// Usage: example get [--count COUNT]
+	// Usage: example get [--count N]
 	// error: error processing --count: strconv.ParseInt: parsing "INVALID": invalid syntax
 }
 
@@ -0,0 +1,138 @@
+package arg
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+)
+
+// Epilogued is the interface that the destination struct, or a subcommand
+// struct, should implement to make text appear at the end of the help message.
+type Epilogued interface {
+	// Epilogue returns the text that will be printed at the end of the help
+	// message, after all other sections.
+	Epilogue() string
+}
+
+// Example is a command line that demonstrates how to use a program
+type Example struct {
+	Command     string // the arguments that follow the program name, e.g. "push --force origin"
+	Explanation string // what the command line does
+}
+
+// Exampled is the interface that the destination struct, or a subcommand
+// struct, should implement to make examples appear in the help message.
+type Exampled interface {
+	// Examples returns the command lines that will be listed in the
+	// "Examples:" section of the help message.
+	Examples() []Example
+}
+
+// ValidateExamples parses the command line of every example in the command
+// tree, including those of hidden subcommands, and returns an error for the
+// first one that cannot be parsed. Each example is parsed into new, empty
+// destination structs, so the structs given to NewParser are not modified.
+// The environment, configuration files, dotenv files and value directories
+// are ignored, so the result depends only on the examples themselves. This is
+// intended to be called from tests so that examples in the help text do not
+// fall out of date.
+func (p *Parser) ValidateExamples() error {
+	var examples []Example
+	var collect func(cmd *command)
+	collect = func(cmd *command) {
+		examples = append(examples, cmd.examples...)
+		for _, subcmd := range cmd.subcommands {
+			collect(subcmd)
+		}
+	}
+	collect(p.cmd)
+
+	// keep only the settings that affect how arguments are parsed
+	config := Config{
+		Program:    p.config.Program,
+		HelpJSON:   p.config.HelpJSON,
+		Messages:   p.config.Messages,
+		NameMapper: p.config.NameMapper,
+		IgnoreEnv:  true,
+		Stderr:     ioutil.Discard,
+	}
+
+	for _, example := range examples {
+		args, err := splitCommandLine(example.Command)
+		if err != nil {
+			return fmt.Errorf("example %q: %v", example.Command, err)
+		}
+
+		var dests []interface{}
+		for _, root := range p.roots {
+			dests = append(dests, reflect.New(root.Type().Elem()).Interface())
+		}
+		q, err := NewParser(config, dests...)
+		if err != nil {
+			return err
+		}
+		q.ignoreFiles = true
+		err = q.Parse(args)
+		if err != nil && err != ErrHelp && err != ErrHelpJSON && err != ErrVersion {
+			return fmt.Errorf("example %q: %v", example.Command, err)
+		}
+	}
+	return nil
+}
+
+// splitCommandLine splits a command line into arguments at unquoted spaces.
+// Single quotes preserve everything up to the closing quote, and within
+// double quotes or outside of quotes a backslash escapes the next character.
+func splitCommandLine(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	var inArg bool
+	var quote rune
+	var escaped bool
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inArg = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,228 @@
+package arg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fetchCmd struct {
+	Item  string `arg:"positional,required" help:"item to fetch"`
+	Force bool   `help:"ignore the cache"`
+}
+
+func (fetchCmd) Examples() []Example {
+	return []Example{
+		{Command: "fetch --force apple", Explanation: "fetch an apple even if it is cached"},
+	}
+}
+
+type exampledArgs struct {
+	Workers int       `arg:"-w" help:"number of workers"`
+	Fetch   *fetchCmd `arg:"subcommand" help:"fetch an item"`
+}
+
+func (exampledArgs) Examples() []Example {
+	return []Example{
+		{Command: "-w 4 fetch apple", Explanation: "fetch an apple with four workers"},
+		{Command: "--help"},
+	}
+}
+
+func (exampledArgs) Epilogue() string {
+	return "Report bugs to bugs@example.com."
+}
+
+func TestHelpWithExamples(t *testing.T) {
+	expectedHelp := `Usage: example [--workers N]
+
+Options:
+  --workers N, -w N      number of workers
+  --help, -h             display this help and exit
+
+Commands:
+  fetch                  fetch an item
+
+Examples:
+  example -w 4 fetch apple
+      fetch an apple with four workers
+  example --help
+
+Report bugs to bugs@example.com.
+`
+	var args exampledArgs
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+}
+
+func TestHelpWithExamplesForSubcommand(t *testing.T) {
+	expectedHelp := `fetch an item
+Usage: example [--workers N] fetch [--force] ITEM
+
+Positional arguments:
+  ITEM                   item to fetch
+
+Options:
+  --force                ignore the cache
+  --help, -h             display this help and exit
+
+Global options:
+  --workers N, -w N      number of workers
+
+Examples:
+  example fetch --force apple
+      fetch an apple even if it is cached
+`
+	var args exampledArgs
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	_ = p.Parse([]string{"fetch", "--help"})
+	var help bytes.Buffer
+	p.writeHelpForCommand(&help, p.lastCmd)
+	assert.Equal(t, expectedHelp, help.String())
+}
+
+func TestManPageWithExamples(t *testing.T) {
+	var args exampledArgs
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	var man bytes.Buffer
+	p.WriteManPage(&man)
+	assert.Contains(t, man.String(), ".SH EXAMPLES\n.TP\n.B \"example \\-w 4 fetch apple\"\nfetch an apple with four workers\n")
+	assert.Contains(t, man.String(), ".PP\nReport bugs to bugs@example.com.\n")
+	assert.Contains(t, man.String(), ".PP\n.B EXAMPLES\n.TP\n.B \"example fetch \\-\\-force apple\"\n")
+}
+
+func TestMarkdownWithExamples(t *testing.T) {
+	var args exampledArgs
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	var md bytes.Buffer
+	p.WriteMarkdown(&md)
+	assert.Contains(t, md.String(), "## Examples\n\n```\nexample -w 4 fetch apple\n```\n\nfetch an apple with four workers\n")
+	assert.Contains(t, md.String(), "\nReport bugs to bugs@example.com.\n")
+	assert.Contains(t, md.String(), "```\nexample fetch --force apple\n```\n")
+}
+
+func TestValidateExamples(t *testing.T) {
+	var args exampledArgs
+	args.Workers = 2
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	assert.NoError(t, p.ValidateExamples())
+
+	// the destination struct is not modified
+	assert.Equal(t, 2, args.Workers)
+	assert.Nil(t, args.Fetch)
+}
+
+type badExampleArgs struct {
+	Workers int
+}
+
+func (badExampleArgs) Examples() []Example {
+	return []Example{
+		{Command: "--workers 4"},
+		{Command: "--threads 4"},
+	}
+}
+
+func TestValidateExamplesFails(t *testing.T) {
+	var args badExampleArgs
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	err = p.ValidateExamples()
+	assert.EqualError(t, err, `example "--threads 4": unknown argument --threads`)
+}
+
+type defaultsExampleArgs struct {
+	Tags  []string
+	Limit *int
+}
+
+func (defaultsExampleArgs) Examples() []Example {
+	return []Example{{Command: "--tags a b --limit 5"}}
+}
+
+func TestValidateExamplesKeepsDefaults(t *testing.T) {
+	limit := 10
+	args := defaultsExampleArgs{Tags: []string{"x", "y"}, Limit: &limit}
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	require.NoError(t, p.ValidateExamples())
+	assert.Equal(t, []string{"x", "y"}, args.Tags)
+	assert.Equal(t, 10, limit)
+}
+
+type requiredExampleArgs struct {
+	Token  string `arg:"env,required"`
+	Config string `arg:"configfile"`
+}
+
+func (requiredExampleArgs) Examples() []Example {
+	return []Example{
+		{Command: "--token abc --config does-not-exist.json"},
+		{Command: "--config does-not-exist.json"},
+	}
+}
+
+func TestValidateExamplesIgnoresEnvironment(t *testing.T) {
+	var args requiredExampleArgs
+	p, err := NewParser(Config{
+		Program:   "example",
+		LookupEnv: func(string) (string, bool) { return "from-env", true },
+	}, &args)
+	require.NoError(t, err)
+
+	err = p.ValidateExamples()
+	assert.EqualError(t, err, `example "--config does-not-exist.json": --token is required`)
+}
+
+type debugCmd struct {
+	Level int
+}
+
+func (debugCmd) Examples() []Example {
+	return []Example{{Command: "debug --verbosity 3"}}
+}
+
+type hiddenExampleArgs struct {
+	Debug *debugCmd `arg:"subcommand,hidden"`
+}
+
+func TestValidateExamplesOfHiddenSubcommands(t *testing.T) {
+	var args hiddenExampleArgs
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	err = p.ValidateExamples()
+	assert.EqualError(t, err, `example "debug --verbosity 3": unknown argument --verbosity`)
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	args, err := splitCommandLine(`push  --message "fix the \"bug\"" 'it'\''s' a\ b`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"push", "--message", `fix the "bug"`, "it's", "a b"}, args)
+
+	args, err = splitCommandLine(`--name ""`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--name", ""}, args)
+
+	_, err = splitCommandLine(`--name "foo`)
+	assert.Error(t, err)
+
+	_, err = splitCommandLine(`--name foo\`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,193 @@
+package arg
+
+import (
+	"reflect"
+)
+
+// Command is a read-only view of the top-level command or one of its
+// subcommands, intended for tools that render help or documentation
+// differently, or that audit the options a program accepts.
+type Command struct {
+	p   *Parser
+	cmd *command
+}
+
+// Option is a read-only view of an option or positional argument.
+type Option struct {
+	p    *Parser
+	spec *spec
+}
+
+// Root returns a view of the top-level command
+func (p *Parser) Root() *Command {
+	return &Command{p: p, cmd: p.cmd}
+}
+
+// Name returns the name of the command. For the top-level command this is the
+// program name.
+func (c *Command) Name() string {
+	return c.cmd.name
+}
+
+// Help returns the help text for the command, as given by the help tag on the
+// subcommand field. It is empty for the top-level command.
+func (c *Command) Help() string {
+	return c.cmd.help
+}
+
+// Summary returns the text from the Summarized interface of the subcommand
+// struct, or an empty string if it does not implement that interface
+func (c *Command) Summary() string {
+	return c.cmd.summary
+}
+
+// Description returns the text from the Described interface of the command's
+// struct, or an empty string if it does not implement that interface
+func (c *Command) Description() string {
+	return c.cmd.description
+}
+
+// Version returns the text from the Versioned interface of the command's
+// struct, or an empty string if it does not implement that interface. Unlike
+// the help text, this is not inherited from ancestor commands.
+func (c *Command) Version() string {
+	return c.cmd.version
+}
+
+// Path returns the names of this command and all its ancestors, starting with
+// the program name.
+func (c *Command) Path() []string {
+	return commandPath(c.cmd)
+}
+
+// Parent returns the command that contains this command, or nil for the
+// top-level command.
+func (c *Command) Parent() *Command {
+	if c.cmd.parent == nil {
+		return nil
+	}
+	return &Command{p: c.p, cmd: c.cmd.parent}
+}
+
+// Options returns the options and positional arguments defined directly on
+// this command, in the order they appear in the struct. Options inherited from
+// ancestor commands are not included.
+func (c *Command) Options() []*Option {
+	var out []*Option
+	for _, spec := range c.cmd.specs {
+		out = append(out, &Option{p: c.p, spec: spec})
+	}
+	return out
+}
+
+// Hidden returns true if the command is omitted from help and documentation
+func (c *Command) Hidden() bool {
+	return c.cmd.hidden
+}
+
+// Deprecated returns the deprecation message for the command, or an empty
+// string if it is not deprecated
+func (c *Command) Deprecated() string {
+	return c.cmd.deprecated
+}
+
+// Subcommands returns the immediate subcommands of this command
+func (c *Command) Subcommands() []*Command {
+	var out []*Command
+	for _, subcmd := range c.cmd.subcommands {
+		out = append(out, &Command{p: c.p, cmd: subcmd})
+	}
+	return out
+}
+
+// Long returns the long name of the option, without the leading hyphens. For
+// positionals this is the name of the argument.
+func (o *Option) Long() string {
+	return o.spec.long
+}
+
+// Short returns the short name of the option, without the leading hyphen, or
+// an empty string if it has no short form.
+func (o *Option) Short() string {
+	return o.spec.short
+}
+
+// Env returns the name of the environment variable for this option, or an
+// empty string if it cannot be set from the environment.
+func (o *Option) Env() string {
+	return o.spec.env
+}
+
+// Help returns the help text for the option
+func (o *Option) Help() string {
+	return o.spec.help
+}
+
+// Required returns true if the option must be provided
+func (o *Option) Required() bool {
+	return o.spec.required
+}
+
+// Positional returns true if this is a positional argument
+func (o *Option) Positional() bool {
+	return o.spec.positional
+}
+
+// Multiple returns true if the option accepts multiple values
+func (o *Option) Multiple() bool {
+	return o.spec.multiple
+}
+
+// Separate returns true if each value must be preceded by the option name
+func (o *Option) Separate() bool {
+	return o.spec.separate
+}
+
+// Boolean returns true if the option is a flag that takes no value
+func (o *Option) Boolean() bool {
+	return o.spec.boolean
+}
+
+// Group returns the name of the section in which the option appears in the
+// help text, or an empty string if it is not in a group
+func (o *Option) Group() string {
+	return o.spec.group
+}
+
+// Placeholder returns the name shown for the value of the option in the help
+// text, such as "N" in "--workers N"
+func (o *Option) Placeholder() string {
+	return o.spec.placeholder
+}
+
+// Hidden returns true if the option is omitted from help and documentation
+func (o *Option) Hidden() bool {
+	return o.spec.hidden
+}
+
+// Deprecated returns the deprecation message for the option, or an empty
+// string if it is not deprecated
+func (o *Option) Deprecated() string {
+	return o.spec.deprecated
+}
+
+// Secret returns true if the default value and environment variable of the
+// option are left out of help and documentation
+func (o *Option) Secret() bool {
+	return o.spec.secret
+}
+
+// Type returns the type of the struct field that the option is stored in
+func (o *Option) Type() reflect.Type {
+	return o.spec.typ
+}
+
+// Default returns the default value of the option as it appears in the help
+// text. The second return value is false if the option has no default.
+func (o *Option) Default() (string, bool) {
+	v := o.p.defaultValue(o.spec)
+	if v == nil {
+		return "", false
+	}
+	return *v, true
+}
@@ -0,0 +1,87 @@
+package arg
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrospectOptions(t *testing.T) {
+	var args struct {
+		Input   string   `arg:"positional,required" help:"input file"`
+		Workers int      `arg:"-w,env:WORKERS" help:"number of workers"`
+		Verbose bool     `arg:"-v"`
+		Tags    []string `arg:"separate"`
+	}
+	args.Workers = 4
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	root := p.Root()
+	assert.Equal(t, "example", root.Name())
+	assert.Equal(t, []string{"example"}, root.Path())
+	assert.Nil(t, root.Parent())
+	assert.Empty(t, root.Subcommands())
+
+	opts := root.Options()
+	require.Len(t, opts, 4)
+
+	assert.Equal(t, "input", opts[0].Long())
+	assert.True(t, opts[0].Positional())
+	assert.True(t, opts[0].Required())
+	assert.Equal(t, "input file", opts[0].Help())
+
+	assert.Equal(t, "workers", opts[1].Long())
+	assert.Equal(t, "w", opts[1].Short())
+	assert.Equal(t, "WORKERS", opts[1].Env())
+	assert.Equal(t, reflect.TypeOf(0), opts[1].Type())
+	def, ok := opts[1].Default()
+	assert.True(t, ok)
+	assert.Equal(t, "4", def)
+
+	assert.True(t, opts[2].Boolean())
+	_, ok = opts[2].Default()
+	assert.False(t, ok)
+
+	assert.True(t, opts[3].Multiple())
+	assert.True(t, opts[3].Separate())
+}
+
+func TestIntrospectSubcommands(t *testing.T) {
+	type pushCmd struct {
+		Remote string
+	}
+	var args struct {
+		Quiet bool     `arg:"-q"`
+		Push  *pushCmd `arg:"subcommand" help:"push changes"`
+	}
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	subcmds := p.Root().Subcommands()
+	require.Len(t, subcmds, 1)
+	push := subcmds[0]
+	assert.Equal(t, "push", push.Name())
+	assert.Equal(t, "push changes", push.Help())
+	assert.Equal(t, []string{"example", "push"}, push.Path())
+	assert.Equal(t, "example", push.Parent().Name())
+	require.Len(t, push.Options(), 1)
+	assert.Equal(t, "remote", push.Options()[0].Long())
+}
+
+func TestIntrospectDescriptions(t *testing.T) {
+	var args struct {
+		versioned
+		Push *describedPushCmd `arg:"subcommand"`
+	}
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	assert.Equal(t, "example 3.2.1", p.Root().Version())
+	push := p.Root().Subcommands()[0]
+	assert.Equal(t, "upload changes", push.Summary())
+	assert.Equal(t, "push uploads local commits to the remote repository", push.Description())
+	assert.Equal(t, "push 2.0", push.Version())
+}
@@ -0,0 +1,161 @@
+package arg
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// CommandSpec is a snapshot of a command and its subcommands that can be
+// serialized to JSON. The JSON produced by WriteSpecJSON has the following
+// form, where fields with empty values are omitted:
+//
+//	{
+//	  "name": "example",                 // program or subcommand name
+//	  "help": "...",                     // help tag of a subcommand
+//	  "summary": "...",                  // from Summarized
+//	  "description": "...",              // from Described
+//	  "version": "...",                  // from Versioned
+//	  "hidden": true,                    // omitted from help and documentation
+//	  "deprecated": "...",               // deprecation message
+//	  "options": [                       // options and positionals, in struct order
+//	    {
+//	      "long": "workers",             // long name, or positional name
+//	      "short": "w",                  // short name
+//	      "env": "WORKERS",              // environment variable
+//	      "help": "...",                 // help text
+//	      "type": "int",                 // Go type of the struct field
+//	      "placeholder": "N",            // name for the value in the help text
+//	      "group": "Database",           // section in the help text
+//	      "default": "4",                // default value as shown in help
+//	      "required": true,
+//	      "positional": true,
+//	      "multiple": true,              // accepts more than one value
+//	      "separate": true,              // each value needs its own flag
+//	      "boolean": true,               // flag that takes no value
+//	      "hidden": true,                // omitted from help and documentation
+//	      "deprecated": "...",           // deprecation message
+//	      "secret": true                 // default and environment variable omitted
+//	    }
+//	  ],
+//	  "subcommands": [ ... ]             // nested CommandSpec objects
+//	}
+type CommandSpec struct {
+	Name        string         `json:"name"`
+	Help        string         `json:"help,omitempty"`
+	Summary     string         `json:"summary,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Version     string         `json:"version,omitempty"`
+	Hidden      bool           `json:"hidden,omitempty"`
+	Deprecated  string         `json:"deprecated,omitempty"`
+	Options     []*OptionSpec  `json:"options,omitempty"`
+	Subcommands []*CommandSpec `json:"subcommands,omitempty"`
+}
+
+// OptionSpec is a snapshot of an option or positional argument that can be
+// serialized to JSON. See CommandSpec for a description of each field.
+type OptionSpec struct {
+	Long        string  `json:"long"`
+	Short       string  `json:"short,omitempty"`
+	Env         string  `json:"env,omitempty"`
+	Help        string  `json:"help,omitempty"`
+	Type        string  `json:"type"`
+	Placeholder string  `json:"placeholder,omitempty"`
+	Group       string  `json:"group,omitempty"`
+	Default     *string `json:"default,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Positional  bool    `json:"positional,omitempty"`
+	Multiple    bool    `json:"multiple,omitempty"`
+	Separate    bool    `json:"separate,omitempty"`
+	Boolean     bool    `json:"boolean,omitempty"`
+	Hidden      bool    `json:"hidden,omitempty"`
+	Deprecated  string  `json:"deprecated,omitempty"`
+	Secret      bool    `json:"secret,omitempty"`
+}
+
+// Spec returns a snapshot of the entire command tree
+func (p *Parser) Spec() *CommandSpec {
+	return specFromCommand(p.Root())
+}
+
+// specFromCommand creates a snapshot of the given command and its subcommands
+func specFromCommand(cmd *Command) *CommandSpec {
+	out := CommandSpec{
+		Name:        cmd.Name(),
+		Help:        cmd.Help(),
+		Summary:     cmd.Summary(),
+		Description: cmd.Description(),
+		Version:     cmd.Version(),
+		Hidden:      cmd.Hidden(),
+		Deprecated:  cmd.Deprecated(),
+	}
+	for _, opt := range cmd.Options() {
+		o := OptionSpec{
+			Long:        opt.Long(),
+			Short:       opt.Short(),
+			Help:        opt.Help(),
+			Type:        opt.Type().String(),
+			Placeholder: opt.Placeholder(),
+			Group:       opt.Group(),
+			Required:    opt.Required(),
+			Positional:  opt.Positional(),
+			Multiple:    opt.Multiple(),
+			Separate:    opt.Separate(),
+			Boolean:     opt.Boolean(),
+			Hidden:      opt.Hidden(),
+			Deprecated:  opt.Deprecated(),
+			Secret:      opt.Secret(),
+		}
+		if !opt.Secret() {
+			o.Env = opt.Env()
+			if def, ok := opt.Default(); ok {
+				o.Default = &def
+			}
+		}
+		out.Options = append(out.Options, &o)
+	}
+	for _, subcmd := range cmd.Subcommands() {
+		out.Subcommands = append(out.Subcommands, specFromCommand(subcmd))
+	}
+	return &out
+}
+
+// WriteSpecJSON writes the entire command tree to the given writer as JSON,
+// in the format described by CommandSpec
+func (p *Parser) WriteSpecJSON(w io.Writer) error {
+	return writeSpecJSON(w, p.Spec())
+}
+
+// writeSpecJSON writes the given command tree to the given writer as JSON
+func writeSpecJSON(w io.Writer, spec *CommandSpec) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(spec)
+}
+
+// visibleSpec returns a copy of the given command tree without hidden options
+// and subcommands, for display to the user via --help=json
+func visibleSpec(spec *CommandSpec) *CommandSpec {
+	out := *spec
+	out.Options = nil
+	for _, opt := range spec.Options {
+		if !opt.Hidden {
+			out.Options = append(out.Options, opt)
+		}
+	}
+	out.Subcommands = nil
+	for _, subcmd := range spec.Subcommands {
+		if !subcmd.Hidden {
+			out.Subcommands = append(out.Subcommands, visibleSpec(subcmd))
+		}
+	}
+	return &out
+}
+
+// ReadSpecJSON reads a command tree previously written by WriteSpecJSON
+func ReadSpecJSON(r io.Reader) (*CommandSpec, error) {
+	var spec CommandSpec
+	if err := json.NewDecoder(r).Decode(&spec); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
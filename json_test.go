@@ -0,0 +1,132 @@
+package arg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSpecJSON(t *testing.T) {
+	expectedJSON := `{
+  "name": "example",
+  "version": "example 3.2.1",
+  "options": [
+    {
+      "long": "workers",
+      "short": "w",
+      "env": "WORKERS",
+      "help": "number of workers",
+      "type": "int",
+      "placeholder": "N",
+      "default": "4"
+    },
+    {
+      "long": "quiet",
+      "type": "bool",
+      "placeholder": "QUIET",
+      "boolean": true
+    }
+  ],
+  "subcommands": [
+    {
+      "name": "get",
+      "help": "fetch an item",
+      "options": [
+        {
+          "long": "items",
+          "type": "[]string",
+          "placeholder": "ITEMS",
+          "required": true,
+          "positional": true,
+          "multiple": true
+        }
+      ]
+    }
+  ]
+}
+`
+	type getCmd struct {
+		Items []string `arg:"positional,required"`
+	}
+	var args struct {
+		versioned
+		Workers int `arg:"-w,env:WORKERS" help:"number of workers"`
+		Quiet   bool
+		Get     *getCmd `arg:"subcommand" help:"fetch an item"`
+	}
+	args.Workers = 4
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, p.WriteSpecJSON(&out))
+	assert.Equal(t, expectedJSON, out.String())
+
+	spec, err := ReadSpecJSON(&out)
+	require.NoError(t, err)
+	assert.Equal(t, p.Spec(), spec)
+}
+
+func TestHelpJSON(t *testing.T) {
+	var args struct {
+		Foo string `arg:"required"`
+	}
+	p, err := NewParser(Config{HelpJSON: true}, &args)
+	require.NoError(t, err)
+	assert.Equal(t, ErrHelpJSON, p.Parse([]string{"--help=json"}))
+
+	p, err = NewParser(Config{}, &args)
+	require.NoError(t, err)
+	assert.EqualError(t, p.Parse([]string{"--foo", "x", "--help=json"}), "unknown argument --help=json")
+}
+
+func TestHelpJSONSkipsHidden(t *testing.T) {
+	type secretCmd struct{}
+	var args struct {
+		Foo    string
+		Debug  bool       `arg:"hidden"`
+		Secret *secretCmd `arg:"subcommand,hidden"`
+	}
+	var stdout bytes.Buffer
+	p, _, code := newRunParser(t, &args, Config{HelpJSON: true, Stdout: &stdout})
+	assert.False(t, p.parseOrExit([]string{"--help=json"}, 2))
+	assert.Equal(t, 0, *code)
+
+	spec, err := ReadSpecJSON(&stdout)
+	require.NoError(t, err)
+	require.Len(t, spec.Options, 1)
+	assert.Equal(t, "foo", spec.Options[0].Long)
+	assert.Empty(t, spec.Subcommands)
+
+	// the full tree remains available for compatibility checking
+	full := p.Spec()
+	assert.Len(t, full.Options, 2)
+	assert.Len(t, full.Subcommands, 1)
+}
+
+func TestSpecOmitsSecrets(t *testing.T) {
+	var args struct {
+		Token string `arg:"env:TOKEN,secret"`
+		Level string `arg:"env:LEVEL"`
+	}
+	args.Token = "hunter2"
+	args.Level = "info"
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	spec := p.Spec()
+	require.Len(t, spec.Options, 2)
+	assert.True(t, spec.Options[0].Secret)
+	assert.Empty(t, spec.Options[0].Env)
+	assert.Nil(t, spec.Options[0].Default)
+	assert.Equal(t, "LEVEL", spec.Options[1].Env)
+	require.NotNil(t, spec.Options[1].Default)
+	assert.Equal(t, "info", *spec.Options[1].Default)
+
+	var out bytes.Buffer
+	require.NoError(t, p.WriteSpecJSON(&out))
+	assert.NotContains(t, out.String(), "hunter2")
+	assert.NotContains(t, out.String(), `"env": "TOKEN"`)
+}
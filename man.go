@@ -0,0 +1,170 @@
+package arg
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteManPage writes a manual page in roff format to the given writer. The
+// page contains a section for the top-level command, followed by a section for
+// each subcommand in the command tree.
+func (p *Parser) WriteManPage(w io.Writer) {
+	fmt.Fprintf(w, ".TH %s 1 \"\" %s\n", roffQuote(strings.ToUpper(p.cmd.name)), roffQuote(p.cmd.version))
+
+	fmt.Fprint(w, ".SH NAME\n")
+	if p.cmd.description != "" {
+		fmt.Fprintf(w, "%s \\- %s\n", roffEscape(p.cmd.name), roffEscape(firstLine(p.cmd.description)))
+	} else {
+		fmt.Fprintln(w, roffEscape(p.cmd.name))
+	}
+
+	fmt.Fprint(w, ".SH SYNOPSIS\n")
+	fmt.Fprintln(w, roffEscape(p.synopsisForCommand(p.cmd)))
+
+	if p.cmd.description != "" {
+		fmt.Fprint(w, ".SH DESCRIPTION\n")
+		fmt.Fprintln(w, roffEscape(p.cmd.description))
+	}
+
+	p.writeManOptions(w, ".SH", p.cmd)
+
+	// the environment section covers the entire command tree
+	var envs []*spec
+	walkCommands(p.cmd, func(cmd *command) {
+		for _, spec := range cmd.specs {
+			if spec.env != "" && !spec.hidden && !spec.secret {
+				envs = append(envs, spec)
+			}
+		}
+	})
+	if len(envs) > 0 {
+		fmt.Fprint(w, ".SH ENVIRONMENT\n")
+		for _, spec := range envs {
+			fmt.Fprint(w, ".TP\n")
+			fmt.Fprintf(w, ".B %s\n", roffEscape(spec.env))
+			if help := p.specHelp(spec); help != "" {
+				fmt.Fprintln(w, roffEscape(help))
+			}
+		}
+	}
+
+	p.writeManExamples(w, ".SH", p.cmd)
+
+	// write a section for each subcommand
+	if len(visibleSubcommands(p.cmd)) > 0 {
+		fmt.Fprint(w, ".SH COMMANDS\n")
+		walkCommands(p.cmd, func(cmd *command) {
+			if cmd == p.cmd {
+				return
+			}
+			fmt.Fprintf(w, ".SS %s\n", roffQuote(strings.Join(commandPath(cmd), " ")))
+			fmt.Fprintln(w, roffEscape(p.synopsisForCommand(cmd)))
+			if help := p.withDeprecation(commandDescription(cmd), cmd.deprecated); help != "" {
+				fmt.Fprint(w, ".PP\n")
+				fmt.Fprintln(w, roffEscape(help))
+			}
+			p.writeManOptions(w, ".PP\n.B", cmd)
+			p.writeManExamples(w, ".PP\n.B", cmd)
+		})
+	}
+}
+
+// writeManExamples writes the examples and epilogue of a command, using the
+// given macro for the heading
+func (p *Parser) writeManExamples(w io.Writer, heading string, cmd *command) {
+	if len(cmd.examples) > 0 {
+		fmt.Fprintf(w, "%s EXAMPLES\n", heading)
+		for _, example := range cmd.examples {
+			fmt.Fprint(w, ".TP\n")
+			fmt.Fprintf(w, ".B %s\n", roffQuote(p.cmd.name+" "+example.Command))
+			if example.Explanation != "" {
+				fmt.Fprintln(w, roffEscape(example.Explanation))
+			}
+		}
+	}
+	if cmd.epilogue != "" {
+		fmt.Fprint(w, ".PP\n")
+		fmt.Fprintln(w, roffEscape(cmd.epilogue))
+	}
+}
+
+// writeManOptions writes the positionals and options of a command as roff
+// tagged paragraphs, using the given macro for the headings
+func (p *Parser) writeManOptions(w io.Writer, heading string, cmd *command) {
+	positionals, options := visibleSpecs(cmd)
+
+	if len(positionals) > 0 {
+		fmt.Fprintf(w, "%s \"POSITIONAL ARGUMENTS\"\n", heading)
+		for _, spec := range positionals {
+			fmt.Fprint(w, ".TP\n")
+			fmt.Fprintf(w, "\\fI%s\\fR\n", roffEscape(spec.placeholder))
+			if help := p.specHelp(spec); help != "" {
+				fmt.Fprintln(w, roffEscape(help))
+			}
+		}
+	}
+
+	if len(options) > 0 {
+		fmt.Fprintf(w, "%s OPTIONS\n", heading)
+		for _, spec := range options {
+			fmt.Fprint(w, ".TP\n")
+			fmt.Fprintln(w, manSynopsis(spec))
+			var lines []string
+			if help := p.specHelp(spec); help != "" {
+				lines = append(lines, roffEscape(help))
+			}
+			if defaultVal := p.defaultValue(spec); defaultVal != nil && !spec.secret {
+				lines = append(lines, roffEscape(fmt.Sprintf("[default: %s]", *defaultVal)))
+			}
+			if len(lines) > 0 {
+				fmt.Fprintln(w, strings.Join(lines, "\n"))
+			}
+		}
+	}
+}
+
+// manSynopsis formats the long and short forms of an option in roff, with the
+// option name in bold and the value placeholder in italics
+func manSynopsis(spec *spec) string {
+	form := func(name string) string {
+		s := "\\fB" + roffEscape(name) + "\\fR"
+		if !spec.boolean {
+			s += " \\fI" + roffEscape(spec.placeholder) + "\\fR"
+		}
+		return s
+	}
+
+	out := form("--" + spec.long)
+	if spec.short != "" {
+		out += ", " + form("-"+spec.short)
+	}
+	return out
+}
+
+// roffEscape escapes backslashes and hyphens, and protects lines that would
+// otherwise be interpreted as roff requests
+func roffEscape(s string) string {
+	s = strings.Replace(s, `\`, `\e`, -1)
+	s = strings.Replace(s, "-", `\-`, -1)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// roffQuote escapes a string for use as a quoted macro argument
+func roffQuote(s string) string {
+	return `"` + strings.Replace(roffEscape(s), `"`, `""`, -1) + `"`
+}
+
+// firstLine gets the first line of a string
+func firstLine(s string) string {
+	if pos := strings.Index(s, "\n"); pos != -1 {
+		return s[:pos]
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+package arg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteManPage(t *testing.T) {
+	expectedMan := `.TH "EXAMPLE" 1 "" ""
+.SH NAME
+example
+.SH SYNOPSIS
+example [\-\-name NAME] [\-\-verbose] [\-\-workers N] INPUT
+.SH "POSITIONAL ARGUMENTS"
+.TP
+\fIINPUT\fR
+.SH OPTIONS
+.TP
+\fB\-\-name\fR \fINAME\fR
+name to use
+[default: C:\eTemp]
+.TP
+\fB\-\-verbose\fR, \fB\-v\fR
+verbosity level
+.TP
+\fB\-\-workers\fR \fIN\fR, \fB\-w\fR \fIN\fR
+number of workers to start
+.SH ENVIRONMENT
+.TP
+.B WORKERS
+number of workers to start
+`
+	var args struct {
+		Input   string `arg:"positional"`
+		Name    string `help:"name to use"`
+		Verbose bool   `arg:"-v" help:"verbosity level"`
+		Workers int    `arg:"-w,env:WORKERS" help:"number of workers to start"`
+	}
+	args.Name = `C:\Temp`
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	var man bytes.Buffer
+	p.WriteManPage(&man)
+	assert.Equal(t, expectedMan, man.String())
+}
+
+func TestWriteManPageWithSubcommands(t *testing.T) {
+	expectedMan := `.TH "EXAMPLE" 1 "" "example 3.2.1"
+.SH NAME
+example \- this program does this and that
+.SH SYNOPSIS
+example [\-\-quiet]
+.SH DESCRIPTION
+this program does this and that
+.SH OPTIONS
+.TP
+\fB\-\-quiet\fR, \fB\-q\fR
+.SH ENVIRONMENT
+.TP
+.B REMOTE
+remote to push to
+.SH COMMANDS
+.SS "example push"
+example [\-\-quiet] push [\-\-remote REMOTE] [\-\-force]
+.PP
+push changes
+.PP
+.B OPTIONS
+.TP
+\fB\-\-remote\fR \fIREMOTE\fR
+remote to push to
+.TP
+\fB\-\-force\fR
+`
+	type pushCmd struct {
+		Remote string `arg:"env" help:"remote to push to"`
+		Force  bool
+	}
+	var args struct {
+		versioned
+		described
+		Quiet bool     `arg:"-q"`
+		Push  *pushCmd `arg:"subcommand" help:"push changes"`
+	}
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	var man bytes.Buffer
+	p.WriteManPage(&man)
+	assert.Equal(t, expectedMan, man.String())
+}
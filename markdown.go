@@ -0,0 +1,133 @@
+package arg
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// WriteMarkdown writes reference documentation in markdown format to the
+// given writer. The document contains one section per command in the
+// subcommand tree, with links between parent and child commands.
+func (p *Parser) WriteMarkdown(w io.Writer) {
+	first := true
+	walkCommands(p.cmd, func(cmd *command) {
+		if !first {
+			fmt.Fprint(w, "\n")
+		}
+		first = false
+		p.writeMarkdownForCommand(w, cmd)
+	})
+}
+
+// writeMarkdownForCommand writes the markdown section for a single command
+func (p *Parser) writeMarkdownForCommand(w io.Writer, cmd *command) {
+	fmt.Fprintf(w, "# %s\n", strings.Join(commandPath(cmd), " "))
+
+	if cmd.parent != nil {
+		parent := strings.Join(commandPath(cmd.parent), " ")
+		fmt.Fprintf(w, "\nParent command: [%s](#%s)\n", parent, markdownAnchor(parent))
+	}
+
+	description := p.withDeprecation(commandDescription(cmd), cmd.deprecated)
+	if description != "" {
+		fmt.Fprintf(w, "\n%s\n", description)
+	}
+
+	fmt.Fprint(w, "\n## Usage\n\n")
+	fmt.Fprintf(w, "```\n%s\n```\n", p.synopsisForCommand(cmd))
+
+	positionals, options := visibleSpecs(cmd)
+
+	if len(positionals) > 0 {
+		fmt.Fprint(w, "\n## Positional arguments\n\n")
+		fmt.Fprint(w, "| Argument | Description |\n")
+		fmt.Fprint(w, "| --- | --- |\n")
+		for _, spec := range positionals {
+			fmt.Fprintf(w, "| `%s` | %s |\n", spec.placeholder, markdownCell(p.specHelp(spec)))
+		}
+	}
+
+	fmt.Fprint(w, "\n## Options\n\n")
+	fmt.Fprint(w, "| Option | Description | Default | Environment |\n")
+	fmt.Fprint(w, "| --- | --- | --- | --- |\n")
+	for _, spec := range options {
+		p.writeMarkdownOption(w, spec)
+	}
+	p.writeMarkdownOption(w, &spec{
+		boolean: true,
+		long:    "help",
+		short:   "h",
+		help:    p.msg(MsgHelpOption),
+	})
+	if versionOf(cmd) != "" {
+		p.writeMarkdownOption(w, &spec{
+			boolean: true,
+			long:    "version",
+			help:    p.msg(MsgVersionOption),
+		})
+	}
+
+	if subcommands := visibleSubcommands(cmd); len(subcommands) > 0 {
+		fmt.Fprint(w, "\n## Commands\n\n")
+		fmt.Fprint(w, "| Command | Description |\n")
+		fmt.Fprint(w, "| --- | --- |\n")
+		for _, subcmd := range subcommands {
+			anchor := markdownAnchor(strings.Join(commandPath(subcmd), " "))
+			fmt.Fprintf(w, "| [%s](#%s) | %s |\n", subcmd.name, anchor, markdownCell(p.commandHelp(subcmd)))
+		}
+	}
+
+	if len(cmd.examples) > 0 {
+		fmt.Fprint(w, "\n## Examples\n")
+		for _, example := range cmd.examples {
+			fmt.Fprintf(w, "\n```\n%s %s\n```\n", p.cmd.name, example.Command)
+			if example.Explanation != "" {
+				fmt.Fprintf(w, "\n%s\n", example.Explanation)
+			}
+		}
+	}
+
+	if cmd.epilogue != "" {
+		fmt.Fprintf(w, "\n%s\n", cmd.epilogue)
+	}
+}
+
+// writeMarkdownOption writes a single row of the options table
+func (p *Parser) writeMarkdownOption(w io.Writer, spec *spec) {
+	left := "`" + synopsis(spec, "--"+spec.long) + "`"
+	if spec.short != "" {
+		left += ", `" + synopsis(spec, "-"+spec.short) + "`"
+	}
+
+	var defaultVal, env string
+	if v := p.defaultValue(spec); v != nil && !spec.secret {
+		defaultVal = "`" + markdownCell(*v) + "`"
+	}
+	if spec.env != "" && !spec.secret {
+		env = "`" + spec.env + "`"
+	}
+	fmt.Fprintf(w, "| %s | %s | %s | %s |\n", left, markdownCell(p.specHelp(spec)), defaultVal, env)
+}
+
+// markdownCell escapes a string for use inside a markdown table cell
+func markdownCell(s string) string {
+	s = strings.Replace(s, "|", `\|`, -1)
+	return strings.Replace(s, "\n", " ", -1)
+}
+
+// markdownAnchor computes the anchor that markdown renderers generate for a
+// heading with the given text
+func markdownAnchor(heading string) string {
+	var out []rune
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			out = append(out, r)
+		case r == ' ':
+			out = append(out, '-')
+		}
+	}
+	return string(out)
+}
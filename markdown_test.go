@@ -0,0 +1,76 @@
+package arg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	expectedMarkdown := "# example\n" +
+		"\n" +
+		"this program does this and that\n" +
+		"\n" +
+		"## Usage\n" +
+		"\n" +
+		"```\n" +
+		"example [--quiet] [--workers N]\n" +
+		"```\n" +
+		"\n" +
+		"## Options\n" +
+		"\n" +
+		"| Option | Description | Default | Environment |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| `--quiet`, `-q` | suppress output |  |  |\n" +
+		"| `--workers N` | number of workers | `4` | `WORKERS` |\n" +
+		"| `--help`, `-h` | display this help and exit |  |  |\n" +
+		"\n" +
+		"## Commands\n" +
+		"\n" +
+		"| Command | Description |\n" +
+		"| --- | --- |\n" +
+		"| [get](#example-get) | fetch an item \\| print it |\n" +
+		"\n" +
+		"# example get\n" +
+		"\n" +
+		"Parent command: [example](#example)\n" +
+		"\n" +
+		"fetch an item | print it\n" +
+		"\n" +
+		"## Usage\n" +
+		"\n" +
+		"```\n" +
+		"example [--quiet] [--workers N] get ITEM\n" +
+		"```\n" +
+		"\n" +
+		"## Positional arguments\n" +
+		"\n" +
+		"| Argument | Description |\n" +
+		"| --- | --- |\n" +
+		"| `ITEM` | item to fetch |\n" +
+		"\n" +
+		"## Options\n" +
+		"\n" +
+		"| Option | Description | Default | Environment |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| `--help`, `-h` | display this help and exit |  |  |\n"
+
+	type getCmd struct {
+		Item string `arg:"positional" help:"item to fetch"`
+	}
+	var args struct {
+		described
+		Quiet   bool    `arg:"-q" help:"suppress output"`
+		Workers int     `arg:"env" help:"number of workers"`
+		Get     *getCmd `arg:"subcommand" help:"fetch an item | print it"`
+	}
+	args.Workers = 4
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	var md bytes.Buffer
+	p.WriteMarkdown(&md)
+	assert.Equal(t, expectedMarkdown, md.String())
+}
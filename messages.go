@@ -0,0 +1,152 @@
+package arg
+
+import "fmt"
+
+// MessageID identifies a message that is shown to users of a program, such as
+// a heading in the help text or an error from Parse
+type MessageID int
+
+// The messages shown to users, with their English text. Messages that take
+// arguments are fmt format strings, and translations must use the same verbs
+// in the same order.
+const (
+	MsgUsage                MessageID = iota // "Usage:"
+	MsgPositionalArguments                   // "Positional arguments:"
+	MsgOptions                               // "Options:"
+	MsgGroupOptions                          // "%s options:", where %s is the group name
+	MsgGlobalOptions                         // "Global options:"
+	MsgCommands                              // "Commands:"
+	MsgExamples                              // "Examples:"
+	MsgEnvironmentVariables                  // "Environment variables:"
+	MsgCompactOptions                        // "[options]", in a compact usage line
+	MsgHelpOption                            // "display this help and exit"
+	MsgVersionOption                         // "display version and exit"
+	MsgDefaultValue                          // "default: %s"
+	MsgEnvVar                                // "env: %s"
+	MsgDeprecatedNote                        // "(deprecated: %s)"
+	MsgErrorPrefix                           // "error:"
+	MsgWarningPrefix                         // "warning:"
+	MsgUnknownArgument                       // "unknown argument %s"
+	MsgInvalidSubcommand                     // "invalid subcommand: %s"
+	MsgMissingValue                          // "missing value for %s"
+	MsgRequired                              // "%s is required"
+	MsgTooManyPositionals                    // "too many positional arguments at '%s'"
+	MsgInvalidValue                          // "error processing %s: %v"
+	MsgInvalidEnvValue                       // "error processing environment variable %s: %v"
+	MsgInvalidEnvCSV                         // "error reading a CSV string from environment variable %s with multiple values: %v"
+	MsgInvalidEnvValues                      // "error processing environment variable %s with multiple values: %v"
+	MsgDeprecatedOption                      // "%s is deprecated: %s"
+	MsgDeprecatedEnvVar                      // "environment variable %s is deprecated: %s"
+	MsgDeprecatedSubcommand                  // "subcommand %s is deprecated: %s"
+	MsgConfigFileError                       // "error reading %s: %v"
+	MsgUnknownConfigKey                      // "%s:%d: unknown key %s"
+	MsgInvalidConfigValue                    // "%s:%d: error processing %s: %v"
+	MsgInvalidFileValue                      // "%s: error processing %s: %v"
+	MsgRenderError                           // "cannot render help: %v"
+	MsgEnvFileError                          // "error reading environment file %s: %v"
+	MsgEnvFileLine                           // "%s:%d: %v", where %v describes the problem
+	MsgEnvFileAssignment                     // "expected KEY=VALUE"
+	MsgEnvFileName                           // "invalid variable name %q"
+	MsgEnvFileQuote                          // "unterminated quote"
+	MsgEnvFileTrailing                       // "unexpected text after quoted value"
+	MsgValueDirError                         // "error reading value directory %s: %v"
+	MsgValueFileError                        // "error reading value file %s: %v"
+	MsgDeprecatedValueFile                   // "value file %s is deprecated: %s"
+	MsgConfigFileLine                        // "%s:%d: %v", where %v describes the problem
+	MsgConfigExpectedObject                  // "expected an object"
+	MsgConfigTrailingData                    // "unexpected data after the object"
+	MsgConfigEndOfFile                       // "unexpected end of file"
+	MsgConfigListElement                     // "lists may only contain strings, numbers and booleans"
+	MsgConfigSection                         // "expected a value, not a section"
+	MsgConfigList                            // "expected a single value, not a list"
+)
+
+// Messages is the interface for translating the messages shown to users.
+// Message returns the text for the given ID, or an empty string to use the
+// English text.
+type Messages interface {
+	Message(id MessageID) string
+}
+
+// MessageMap is a Messages implementation backed by a map. Messages that are
+// missing from the map are shown in English.
+type MessageMap map[MessageID]string
+
+// Message returns the text for the given ID
+func (m MessageMap) Message(id MessageID) string {
+	return m[id]
+}
+
+// englishMessages holds the default text for each message
+var englishMessages = MessageMap{
+	MsgUsage:                "Usage:",
+	MsgPositionalArguments:  "Positional arguments:",
+	MsgOptions:              "Options:",
+	MsgGroupOptions:         "%s options:",
+	MsgGlobalOptions:        "Global options:",
+	MsgCommands:             "Commands:",
+	MsgExamples:             "Examples:",
+	MsgEnvironmentVariables: "Environment variables:",
+	MsgCompactOptions:       "[options]",
+	MsgHelpOption:           "display this help and exit",
+	MsgVersionOption:        "display version and exit",
+	MsgDefaultValue:         "default: %s",
+	MsgEnvVar:               "env: %s",
+	MsgDeprecatedNote:       "(deprecated: %s)",
+	MsgErrorPrefix:          "error:",
+	MsgWarningPrefix:        "warning:",
+	MsgUnknownArgument:      "unknown argument %s",
+	MsgInvalidSubcommand:    "invalid subcommand: %s",
+	MsgMissingValue:         "missing value for %s",
+	MsgRequired:             "%s is required",
+	MsgTooManyPositionals:   "too many positional arguments at '%s'",
+	MsgInvalidValue:         "error processing %s: %v",
+	MsgInvalidEnvValue:      "error processing environment variable %s: %v",
+	MsgInvalidEnvCSV:        "error reading a CSV string from environment variable %s with multiple values: %v",
+	MsgInvalidEnvValues:     "error processing environment variable %s with multiple values: %v",
+	MsgDeprecatedOption:     "%s is deprecated: %s",
+	MsgDeprecatedEnvVar:     "environment variable %s is deprecated: %s",
+	MsgDeprecatedSubcommand: "subcommand %s is deprecated: %s",
+	MsgConfigFileError:      "error reading %s: %v",
+	MsgUnknownConfigKey:     "%s:%d: unknown key %s",
+	MsgInvalidConfigValue:   "%s:%d: error processing %s: %v",
+	MsgInvalidFileValue:     "%s: error processing %s: %v",
+	MsgRenderError:          "cannot render help: %v",
+	MsgEnvFileError:         "error reading environment file %s: %v",
+	MsgEnvFileLine:          "%s:%d: %v",
+	MsgEnvFileAssignment:    "expected KEY=VALUE",
+	MsgEnvFileName:          "invalid variable name %q",
+	MsgEnvFileQuote:         "unterminated quote",
+	MsgEnvFileTrailing:      "unexpected text after quoted value",
+	MsgValueDirError:        "error reading value directory %s: %v",
+	MsgValueFileError:       "error reading value file %s: %v",
+	MsgDeprecatedValueFile:  "value file %s is deprecated: %s",
+	MsgConfigFileLine:       "%s:%d: %v",
+	MsgConfigExpectedObject: "expected an object",
+	MsgConfigTrailingData:   "unexpected data after the object",
+	MsgConfigEndOfFile:      "unexpected end of file",
+	MsgConfigListElement:    "lists may only contain strings, numbers and booleans",
+	MsgConfigSection:        "expected a value, not a section",
+	MsgConfigList:           "expected a single value, not a list",
+}
+
+// msg gets the text of a message in the configured language, formatted with
+// the given arguments
+func (p *Parser) msg(id MessageID, args ...interface{}) string {
+	var format string
+	if p.config.Messages != nil {
+		format = p.config.Messages.Message(id)
+	}
+	if format == "" {
+		format = englishMessages[id]
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// errorf creates an error from a message in the configured language
+func (p *Parser) errorf(id MessageID, args ...interface{}) error {
+	return fmt.Errorf("%s", p.msg(id, args...))
+}
@@ -0,0 +1,65 @@
+package arg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var germanMessages = MessageMap{
+	MsgUsage:           "Aufruf:",
+	MsgOptions:         "Optionen:",
+	MsgHelpOption:      "diese Hilfe anzeigen und beenden",
+	MsgDefaultValue:    "Standard: %s",
+	MsgUnknownArgument: "unbekanntes Argument %s",
+	MsgRequired:        "%s ist erforderlich",
+}
+
+func TestTranslatedHelp(t *testing.T) {
+	expectedHelp := `Aufruf: example [--workers N] SRC
+
+Positional arguments:
+  SRC
+
+Optionen:
+  --workers N            number of workers [Standard: 4]
+  --help, -h             diese Hilfe anzeigen und beenden
+`
+	var args struct {
+		Src     string `arg:"positional"`
+		Workers int    `help:"number of workers"`
+	}
+	args.Workers = 4
+	p, err := NewParser(Config{Program: "example", Messages: germanMessages}, &args)
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+}
+
+func TestTranslatedErrors(t *testing.T) {
+	var args struct {
+		Workers int `arg:"required"`
+	}
+	p, err := NewParser(Config{Messages: germanMessages}, &args)
+	require.NoError(t, err)
+
+	err = p.Parse([]string{"--threads"})
+	assert.EqualError(t, err, "unbekanntes Argument --threads")
+
+	err = p.Parse(nil)
+	assert.EqualError(t, err, "--workers ist erforderlich")
+
+	// messages missing from the map are shown in English
+	err = p.Parse([]string{"--workers"})
+	assert.EqualError(t, err, "missing value for --workers")
+}
+
+func TestEnglishMessagesComplete(t *testing.T) {
+	for id := MsgUsage; id <= MsgConfigList; id++ {
+		assert.NotEmpty(t, englishMessages[id], "message %d", id)
+	}
+}
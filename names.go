@@ -0,0 +1,150 @@
+package arg
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// NameMapper is the interface for deriving names from struct fields when they
+// are not given explicitly in the arg tag
+type NameMapper interface {
+	// Name returns the long name of an option, the name of a positional
+	// argument, or the name of a subcommand
+	Name(field reflect.StructField) string
+	// Env returns the name of the environment variable for a field tagged with
+	// env but no variable name
+	Env(field reflect.StructField) string
+}
+
+// naming holds the settings that decide the names of the options, subcommands
+// and environment variables of a command
+type naming struct {
+	mapper    NameMapper
+	envPrefix string // prepended to derived environment variable names
+	envAll    bool   // derive environment variables for options without an env tag
+}
+
+// env derives the name of the environment variable for a field
+func (n naming) env(field reflect.StructField) string {
+	return n.envPrefix + n.mapper.Env(field)
+}
+
+// forSubcommand gets the naming for the fields of a subcommand, whose
+// environment variables include the subcommand name after the prefix
+func (n naming) forSubcommand(name string) naming {
+	if n.envPrefix != "" {
+		n.envPrefix += strings.ToUpper(strings.Replace(name, "-", "_", -1)) + "_"
+	}
+	return n
+}
+
+// The built-in name mappers. For a field named MaxWorkers:
+//
+//	LowerCase   --maxworkers   MAXWORKERS   (the default)
+//	KebabCase   --max-workers  MAX_WORKERS
+//	SnakeCase   --max_workers  MAX_WORKERS
+var (
+	LowerCase NameMapper = lowerCase{}
+	KebabCase NameMapper = joinedCase{"-"}
+	SnakeCase NameMapper = joinedCase{"_"}
+)
+
+type lowerCase struct{}
+
+func (lowerCase) Name(field reflect.StructField) string {
+	return strings.ToLower(field.Name)
+}
+
+func (lowerCase) Env(field reflect.StructField) string {
+	return strings.ToUpper(field.Name)
+}
+
+// joinedCase joins the lower case words of a field name with a separator
+type joinedCase struct {
+	sep string
+}
+
+func (c joinedCase) Name(field reflect.StructField) string {
+	return strings.ToLower(strings.Join(splitWords(field.Name), c.sep))
+}
+
+func (c joinedCase) Env(field reflect.StructField) string {
+	return strings.ToUpper(strings.Join(splitWords(field.Name), "_"))
+}
+
+// TagName returns a NameMapper that reuses the name from another struct tag,
+// such as "json" or "yaml", so that command line options match the keys of a
+// configuration file. Fields without that tag are named by the fallback.
+func TagName(key string, fallback NameMapper) NameMapper {
+	return tagName{key: key, fallback: fallback}
+}
+
+type tagName struct {
+	key      string
+	fallback NameMapper
+}
+
+// lookup gets the name from the tag, ignoring options such as "omitempty"
+func (m tagName) lookup(field reflect.StructField) string {
+	name := field.Tag.Get(m.key)
+	if pos := strings.Index(name, ","); pos != -1 {
+		name = name[:pos]
+	}
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func (m tagName) Name(field reflect.StructField) string {
+	if name := m.lookup(field); name != "" {
+		return name
+	}
+	return m.fallback.Name(field)
+}
+
+func (m tagName) Env(field reflect.StructField) string {
+	if name := m.lookup(field); name != "" {
+		return strings.ToUpper(strings.Replace(name, "-", "_", -1))
+	}
+	return m.fallback.Env(field)
+}
+
+// splitWords splits a Go identifier into words at changes of case, keeping
+// acronyms together, so that "HTTPServerURL" becomes "HTTP", "Server", "URL".
+// Digits stay with the preceding word.
+func splitWords(s string) []string {
+	runes := []rune(s)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		var boundary bool
+		switch {
+		case cur == '_':
+			words = appendWord(words, runes[start:i])
+			start = i + 1
+			continue
+		case unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			boundary = true
+		case unicode.IsUpper(cur) && unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+			boundary = true
+		}
+		if boundary && i > start {
+			words = appendWord(words, runes[start:i])
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = appendWord(words, runes[start:])
+	}
+	return words
+}
+
+func appendWord(words []string, word []rune) []string {
+	if len(word) == 0 {
+		return words
+	}
+	return append(words, string(word))
+}
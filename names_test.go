@@ -0,0 +1,76 @@
+package arg
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitWords(t *testing.T) {
+	assert.Equal(t, []string{"Max", "Workers"}, splitWords("MaxWorkers"))
+	assert.Equal(t, []string{"HTTP", "Server", "URL"}, splitWords("HTTPServerURL"))
+	assert.Equal(t, []string{"User", "ID"}, splitWords("UserID"))
+	assert.Equal(t, []string{"Retry3", "Times"}, splitWords("Retry3Times"))
+	assert.Equal(t, []string{"Max", "Workers"}, splitWords("Max_Workers"))
+	assert.Equal(t, []string{"x"}, splitWords("x"))
+}
+
+func TestKebabCase(t *testing.T) {
+	type pushCmd struct {
+		RemoteName string `arg:"positional"`
+	}
+	var args struct {
+		MaxWorkers int      `arg:"env"`
+		DryRun     bool     `arg:"--dry"`
+		PushAll    *pushCmd `arg:"subcommand"`
+	}
+	setenv(t, "MAX_WORKERS", "3")
+	defer os.Unsetenv("MAX_WORKERS")
+
+	p, err := NewParser(Config{NameMapper: KebabCase}, &args)
+	require.NoError(t, err)
+	err = p.Parse([]string{"--dry", "push-all", "origin"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, args.MaxWorkers)
+	assert.True(t, args.DryRun)
+	require.NotNil(t, args.PushAll)
+	assert.Equal(t, "origin", args.PushAll.RemoteName)
+
+	err = p.Parse([]string{"--max-workers", "5"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, args.MaxWorkers)
+
+	push := p.Root().Subcommands()[0]
+	assert.Equal(t, "push-all", push.Name())
+	assert.Equal(t, "remote-name", push.Options()[0].Long())
+}
+
+func TestSnakeCase(t *testing.T) {
+	var args struct {
+		MaxWorkers int `arg:"env"`
+	}
+	p, err := NewParser(Config{NameMapper: SnakeCase}, &args)
+	require.NoError(t, err)
+	err = p.Parse([]string{"--max_workers", "5"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, args.MaxWorkers)
+	assert.Equal(t, "MAX_WORKERS", p.Root().Options()[0].Env())
+}
+
+func TestTagName(t *testing.T) {
+	var args struct {
+		MaxWorkers int    `json:"workers,omitempty" arg:"env"`
+		LogLevel   string `json:"-"`
+		Verbose    bool
+	}
+	p, err := NewParser(Config{NameMapper: TagName("json", KebabCase)}, &args)
+	require.NoError(t, err)
+	err = p.Parse([]string{"--workers", "5", "--log-level", "debug", "--verbose"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, args.MaxWorkers)
+	assert.Equal(t, "debug", args.LogLevel)
+	assert.True(t, args.Verbose)
+	assert.Equal(t, "WORKERS", p.Root().Options()[0].Env())
+}
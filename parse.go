@@ -4,10 +4,12 @@ import (
 	"encoding/csv"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"unicode"
 
 	scalar "github.com/alexflint/go-scalar"
 )
@@ -27,6 +29,13 @@ type ArgUnmarshaler interface {
 	UnmarshalArg(text []byte) error
 }
 
+// ArgPlaceholder is the interface that custom argument types can implement to
+// choose the name shown for their value in the help text, such as "LEVEL" in
+// "--optimize LEVEL"
+type ArgPlaceholder interface {
+	Placeholder() string
+}
+
 // String gets a string representation of the given path
 func (p path) String() string {
 	if len(p.fields) == 0 {
@@ -48,17 +57,24 @@ func (p path) Child(child string) path {
 
 // spec represents a command line option
 type spec struct {
-	dest       path
-	typ        reflect.Type
-	long       string
-	short      string
-	multiple   bool
-	required   bool
-	positional bool
-	separate   bool
-	help       string
-	env        string
-	boolean    bool
+	dest        path
+	typ         reflect.Type
+	long        string
+	short       string
+	multiple    bool
+	required    bool
+	positional  bool
+	separate    bool
+	help        string
+	env         string
+	boolean     bool
+	group       string // name of the section in the help text, or empty for the default section
+	hidden      bool
+	deprecated  string // message printed when the option is used, or empty if not deprecated
+	placeholder string // name for the value of the option in the help text
+	secret      bool   // the default value and environment variable are left out of the help text
+	configFile  bool   // the value names configuration files to load
+	bootstrap   bool   // set in the first pass of ParseWithBootstrap
 }
 
 // command represents a named subcommand, or the top-level command
@@ -68,7 +84,21 @@ type command struct {
 	dest        path
 	specs       []*spec
 	subcommands []*command
+	groups      []*group
 	parent      *command
+	hidden      bool
+	deprecated  string // message printed when the subcommand is used, or empty if not deprecated
+	version     string // from the Versioned interface
+	description string // from the Described interface
+	summary     string // from the Summarized interface
+	epilogue    string // from the Epilogued interface
+	examples    []Example
+}
+
+// group represents a named section of options in the help text
+type group struct {
+	name        string
+	description string // from the GroupDescribed interface on an embedded struct
 }
 
 // ErrHelp indicates that -h or --help were provided
@@ -77,28 +107,50 @@ var ErrHelp = errors.New("help requested by user")
 // ErrVersion indicates that --version was provided
 var ErrVersion = errors.New("version requested by user")
 
+// ErrHelpJSON indicates that --help=json was provided and Config.HelpJSON is set
+var ErrHelpJSON = errors.New("json help requested by user")
+
 // MustParse processes command line arguments and exits upon failure
 func MustParse(dest ...interface{}) *Parser {
 	p, err := NewParser(Config{}, dest...)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(stderr, err)
 		osExit(-1)
 		return nil // just in case osExit was monkey-patched
 	}
 
-	err = p.Parse(flags())
+	p.MustParse(flags())
+	return p
+}
+
+// MustParse processes the given command line arguments, and upon failure
+// prints an error with the usage of the relevant command and exits. For --help
+// and --version it prints the help or version and exits.
+func (p *Parser) MustParse(args []string) {
+	p.parseOrExit(args, -1)
+}
+
+// parseOrExit processes command line arguments and returns true if they were
+// valid. Otherwise it prints help, version or an error and exits, using the
+// given exit status for errors unless Config.UsageExitCode is set.
+func (p *Parser) parseOrExit(args []string, usageCode int) bool {
+	err := p.Parse(args)
 	switch {
+	case err == nil:
+		return true
 	case err == ErrHelp:
-		p.writeHelpForCommand(os.Stdout, p.lastCmd)
-		osExit(0)
+		p.writeHelpForCommand(p.stdout(), p.lastCmd)
+		p.exit(p.config.HelpExitCode)
 	case err == ErrVersion:
-		fmt.Println(p.version)
-		osExit(0)
-	case err != nil:
-		p.failWithCommand(err.Error(), p.lastCmd)
+		fmt.Fprintln(p.stdout(), versionOf(p.lastCmd))
+		p.exit(p.config.HelpExitCode)
+	case err == ErrHelpJSON:
+		writeSpecJSON(p.stdout(), visibleSpec(p.Spec()))
+		p.exit(p.config.HelpExitCode)
+	default:
+		p.failWithCommand(err.Error(), p.lastCmd, usageCode)
 	}
-
-	return p
+	return false
 }
 
 // Parse processes command line arguments and stores them in dest
@@ -121,6 +173,113 @@ func flags() []string {
 // Config represents configuration options for an argument parser
 type Config struct {
 	Program string // Program is the name of the program used in the help text
+
+	// HelpJSON enables --help=json, which prints the command tree in the
+	// format written by WriteSpecJSON
+	HelpJSON bool
+
+	// HelpWidth is the width in columns to which help text is wrapped. If it
+	// is zero then help text is wrapped to the width of the terminal, or to
+	// $COLUMNS if set, when written to a terminal, and not wrapped otherwise.
+	// If it is negative then help text is never wrapped.
+	HelpWidth int
+
+	// ColumnsWhenPiped wraps help text to $COLUMNS, if set, even when it is
+	// not written to a terminal, as in "COLUMNS=200 example -h | less"
+	ColumnsWhenPiped bool
+
+	// GroupOrder lists the names of option groups in the order in which they
+	// appear in the help text. Groups not listed here appear afterwards, in the
+	// order in which they are first used in the struct.
+	GroupOrder []string
+
+	// CompactUsage is the number of optional options above which the usage
+	// line shows "[options]" instead of listing each optional option. Zero
+	// means that options are always listed.
+	CompactUsage int
+
+	// HelpRenderer replaces the built-in layout of the usage and help text
+	HelpRenderer HelpRenderer
+
+	// Color controls whether help and error messages use ANSI colors
+	Color ColorMode
+
+	// EnvHelp adds a section to the end of the help text that lists the
+	// environment variables of the command, its ancestors and its subcommands
+	EnvHelp bool
+
+	// Messages translates the help text and error messages shown to users. If
+	// nil, messages are shown in English.
+	Messages Messages
+
+	// NameMapper derives the names of options, positionals, subcommands and
+	// environment variables from struct fields. If nil, LowerCase is used.
+	NameMapper NameMapper
+
+	// EnvPrefix is prepended to the names of environment variables that are
+	// derived from field names, together with the names of the subcommands
+	// that contain the field, as in MYAPP_PUSH_REMOTE. Names given explicitly
+	// in the env tag are used as they are.
+	EnvPrefix string
+
+	// EnvAllOptions reads every option from the environment, not only those
+	// tagged with env
+	EnvAllOptions bool
+
+	// LookupEnv is used for every environment variable lookup, including
+	// NO_COLOR and COLUMNS. If nil, os.LookupEnv is used.
+	LookupEnv func(name string) (string, bool)
+
+	// IgnoreEnv disables all environment variable lookups, so that parsing
+	// depends only on the command line arguments
+	IgnoreEnv bool
+
+	// Stdout receives help and version output from MustParse. If nil,
+	// os.Stdout is used.
+	Stdout io.Writer
+
+	// Stderr receives errors, warnings and the usage printed with errors. If
+	// nil, os.Stderr is used.
+	Stderr io.Writer
+
+	// Exit is called to end the program after printing help, version or an
+	// error. If nil, os.Exit is used.
+	Exit func(code int)
+
+	// UsageExitCode is the exit status after an error on the command line. If
+	// zero, Run exits with 2, and MustParse and Fail exit with -1, which most
+	// systems report as 255.
+	UsageExitCode int
+
+	// HelpExitCode is the exit status after printing help or version
+	HelpExitCode int
+
+	// ConfigFiles are configuration files that set options not given on the
+	// command line or in the environment. Files that do not exist are
+	// skipped, and later files override earlier ones. Files named by an option
+	// tagged with configfile are loaded after these.
+	ConfigFiles []string
+
+	// ConfigDecoders reads configuration files in formats other than JSON,
+	// keyed by file extension such as ".yaml"
+	ConfigDecoders map[string]ConfigDecoder
+
+	// EnvFiles are dotenv files holding KEY=VALUE lines, whose variables are
+	// used when they are not set in the environment. Files that do not exist
+	// are skipped, and later files override earlier ones.
+	EnvFiles []string
+
+	// ValueDirs are directories holding one file per option, such as
+	// $CREDENTIALS_DIRECTORY or /run/secrets. A file named after the
+	// environment variable or long name of an option holds its value, with
+	// the trailing newline removed. Empty entries and directories that do not
+	// exist are skipped, and later directories override earlier ones.
+	ValueDirs []string
+
+	// ValueDirPrecedence sets whether values from ValueDirs override the
+	// environment or the command line. By default the environment overrides
+	// them.
+	ValueDirPrecedence ValueDirPrecedence
 }
 
 // Parser represents a set of command line options with destination values
@@ -128,29 +287,52 @@ type Parser struct {
 	cmd         *command
 	roots       []reflect.Value
 	config      Config
-	version     string
-	description string
+	ignoreFiles bool // set by ValidateExamples so that no files are read
 
 	// the following fields change curing processing of command line arguments
-	lastCmd *command
+	lastCmd     *command
+	warnings    []string
+	quiet       bool                  // set during the first pass of ParseWithBootstrap, which drops warnings
+	envFileVars map[string]envFileVar // variables from Config.EnvFiles
+	valueFiles  map[string]string     // paths of files in Config.ValueDirs, by name
 }
 
-// Versioned is the interface that the destination struct should implement to
-// make a version string appear at the top of the help message.
+// Versioned is the interface that the destination struct, or a subcommand
+// struct, should implement to make a version string appear at the top of the
+// help message.
 type Versioned interface {
 	// Version returns the version string that will be printed on a line by itself
 	// at the top of the help message.
 	Version() string
 }
 
-// Described is the interface that the destination struct should implement to
-// make a description string appear at the top of the help message.
+// Described is the interface that the destination struct, or a subcommand
+// struct, should implement to make a description string appear at the top of
+// the help message.
 type Described interface {
 	// Description returns the string that will be printed on a line by itself
 	// at the top of the help message.
 	Description() string
 }
 
+// GroupDescribed is the interface that an embedded struct should implement to
+// introduce the section of the help message that holds its options. It is
+// separate from Described, which an embedded struct passes on to the command.
+type GroupDescribed interface {
+	// GroupDescription returns the text that will be printed below the
+	// heading of the group
+	GroupDescription() string
+}
+
+// Summarized is the interface that a subcommand struct should implement to
+// provide a short summary for the list of commands in the help message of its
+// parent, separate from the description at the top of its own help message.
+type Summarized interface {
+	// Summary returns the text that will be printed next to the subcommand
+	// name in the list of commands.
+	Summary() string
+}
+
 // walkFields calls a function for each field of a struct, recursively expanding struct fields.
 func walkFields(t reflect.Type, visit func(field reflect.StructField, owner reflect.Type) bool) {
 	for i := 0; i < t.NumField(); i++ {
@@ -181,6 +363,15 @@ func NewParser(config Config, dests ...interface{}) (*Parser, error) {
 		config: config,
 	}
 
+	names := naming{
+		mapper:    config.NameMapper,
+		envPrefix: config.EnvPrefix,
+		envAll:    config.EnvAllOptions,
+	}
+	if names.mapper == nil {
+		names.mapper = LowerCase
+	}
+
 	// make a list of roots
 	for _, dest := range dests {
 		p.roots = append(p.roots, reflect.ValueOf(dest))
@@ -193,25 +384,77 @@ func NewParser(config Config, dests ...interface{}) (*Parser, error) {
 			panic(fmt.Sprintf("%s is not a pointer (did you forget an ampersand?)", t))
 		}
 
-		cmd, err := cmdFromStruct(name, path{root: i}, t)
+		cmd, err := cmdFromStruct(name, path{root: i}, t, names)
 		if err != nil {
 			return nil, err
 		}
 		p.cmd.specs = append(p.cmd.specs, cmd.specs...)
 		p.cmd.subcommands = append(p.cmd.subcommands, cmd.subcommands...)
-
-		if dest, ok := dest.(Versioned); ok {
-			p.version = dest.Version()
-		}
-		if dest, ok := dest.(Described); ok {
-			p.description = dest.Description()
+		for _, subcmd := range cmd.subcommands {
+			subcmd.parent = p.cmd
 		}
+		p.cmd.groups = append(p.cmd.groups, cmd.groups...)
+
+		describeCommand(p.cmd, dest)
+	}
+
+	if err := checkBootstrap(p.cmd, true); err != nil {
+		return nil, err
 	}
 
 	return &p, nil
 }
 
-func cmdFromStruct(name string, dest path, t reflect.Type) (*command, error) {
+// describeCommand reads the version, description, summary, epilogue and
+// examples of a command from the interfaces implemented by its destination
+func describeCommand(cmd *command, dest interface{}) {
+	if dest, ok := dest.(Versioned); ok {
+		cmd.version = dest.Version()
+	}
+	if dest, ok := dest.(Described); ok {
+		cmd.description = dest.Description()
+	}
+	if dest, ok := dest.(Summarized); ok {
+		cmd.summary = dest.Summary()
+	}
+	if dest, ok := dest.(Epilogued); ok {
+		cmd.epilogue = dest.Epilogue()
+	}
+	if dest, ok := dest.(Exampled); ok {
+		cmd.examples = append(cmd.examples, dest.Examples()...)
+	}
+}
+
+// versionOf gets the version of a command, which is inherited from the
+// nearest ancestor that has one
+func versionOf(cmd *command) string {
+	for ; cmd != nil; cmd = cmd.parent {
+		if cmd.version != "" {
+			return cmd.version
+		}
+	}
+	return ""
+}
+
+// groupName derives the name of the group for the fields of an embedded
+// struct from its type name, so that "DatabaseOptions" becomes "Database"
+func groupName(t reflect.Type) string {
+	words := splitWords(t.Name())
+	if n := len(words); n > 1 {
+		switch strings.ToLower(words[n-1]) {
+		case "options", "opts", "args", "flags":
+			words = words[:n-1]
+		}
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	first := []rune(words[0])
+	words[0] = string(unicode.ToUpper(first[0])) + string(first[1:])
+	return strings.Join(words, " ")
+}
+
+func cmdFromStruct(name string, dest path, t reflect.Type, names naming) (*command, error) {
 	// commands can only be created from pointers to structs
 	if t.Kind() != reflect.Ptr {
 		return nil, fmt.Errorf("subcommands must be pointers to structs but %s is a %s",
@@ -229,6 +472,30 @@ func cmdFromStruct(name string, dest path, t reflect.Type) (*command, error) {
 		dest: dest,
 	}
 
+	// groupOf tracks the group assigned to the fields of each embedded struct,
+	// and introOf the description of each group from the embedded struct
+	// that introduced it
+	groupOf := make(map[reflect.Type]string)
+	introOf := make(map[string]string)
+
+	// addGroup adds a group to the command when the first option in it is
+	// found, so that embedded structs without options do not create groups
+	addGroup := func(name string) {
+		var g *group
+		for _, existing := range cmd.groups {
+			if existing.name == name {
+				g = existing
+			}
+		}
+		if g == nil {
+			g = &group{name: name}
+			cmd.groups = append(cmd.groups, g)
+		}
+		if g.description == "" {
+			g.description = introOf[name]
+		}
+	}
+
 	var errs []string
 	walkFields(t, func(field reflect.StructField, t reflect.Type) bool {
 		// Check for the ignore switch in the tag
@@ -237,8 +504,23 @@ func cmdFromStruct(name string, dest path, t reflect.Type) (*command, error) {
 			return false
 		}
 
-		// If this is an embedded struct then recurse into its fields
+		// If this is an embedded struct then recurse into its fields, which
+		// belong to the group named on the embedded field, or else to the
+		// group of the enclosing struct, or else to a group named after the
+		// embedded struct
 		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			name := field.Tag.Get("group")
+			if name == "" {
+				name = groupOf[t]
+			}
+			if name == "" {
+				name = groupName(field.Type)
+			}
+			described, ok := reflect.New(field.Type).Interface().(GroupDescribed)
+			if _, found := introOf[name]; ok && !found && name != groupOf[t] {
+				introOf[name] = described.GroupDescription()
+			}
+			groupOf[field.Type] = name
 			return true
 		}
 
@@ -246,7 +528,7 @@ func cmdFromStruct(name string, dest path, t reflect.Type) (*command, error) {
 		subdest := dest.Child(field.Name)
 		spec := spec{
 			dest: subdest,
-			long: strings.ToLower(field.Name),
+			long: names.mapper.Name(field),
 			typ:  field.Type,
 		}
 
@@ -255,6 +537,18 @@ func cmdFromStruct(name string, dest path, t reflect.Type) (*command, error) {
 			spec.help = help
 		}
 
+		if deprecated, ok := field.Tag.Lookup("deprecated"); ok {
+			spec.deprecated = deprecated
+			if spec.deprecated == "" {
+				spec.deprecated = "no longer supported"
+			}
+		}
+
+		spec.group = groupOf[t]
+		if name := field.Tag.Get("group"); name != "" {
+			spec.group = name
+		}
+
 		// Look at the tag
 		var isSubcommand bool // tracks whether this field is a subcommand
 		if tag != "" {
@@ -284,6 +578,14 @@ func cmdFromStruct(name string, dest path, t reflect.Type) (*command, error) {
 					spec.positional = true
 				case key == "separate":
 					spec.separate = true
+				case key == "hidden":
+					spec.hidden = true
+				case key == "secret":
+					spec.secret = true
+				case key == "configfile":
+					spec.configFile = true
+				case key == "bootstrap":
+					spec.bootstrap = true
 				case key == "help": // deprecated
 					spec.help = value
 				case key == "env":
@@ -291,17 +593,17 @@ func cmdFromStruct(name string, dest path, t reflect.Type) (*command, error) {
 					if value != "" {
 						spec.env = value
 					} else {
-						spec.env = strings.ToUpper(field.Name)
+						spec.env = names.env(field)
 					}
 				case key == "subcommand":
 					// decide on a name for the subcommand
 					cmdname := value
 					if cmdname == "" {
-						cmdname = strings.ToLower(field.Name)
+						cmdname = names.mapper.Name(field)
 					}
 
 					// parse the subcommand recursively
-					subcmd, err := cmdFromStruct(cmdname, subdest, field.Type)
+					subcmd, err := cmdFromStruct(cmdname, subdest, field.Type, names.forSubcommand(cmdname))
 					if err != nil {
 						errs = append(errs, err.Error())
 						return false
@@ -309,6 +611,8 @@ func cmdFromStruct(name string, dest path, t reflect.Type) (*command, error) {
 
 					subcmd.parent = &cmd
 					subcmd.help = field.Tag.Get("help")
+					subcmd.deprecated = spec.deprecated
+					describeCommand(subcmd, reflect.New(field.Type.Elem()).Interface())
 
 					cmd.subcommands = append(cmd.subcommands, subcmd)
 					isSubcommand = true
@@ -319,12 +623,24 @@ func cmdFromStruct(name string, dest path, t reflect.Type) (*command, error) {
 			}
 		}
 
+		// the hidden key may appear before or after the subcommand key
+		if isSubcommand {
+			cmd.subcommands[len(cmd.subcommands)-1].hidden = spec.hidden
+		}
+
+		if names.envAll && spec.env == "" && !spec.positional && !isSubcommand {
+			spec.env = names.env(field)
+		}
+
 		// Check whether this field is supported. It's good to do this here rather than
 		// wait until ParseValue because it means that a program with invalid argument
 		// fields will always fail regardless of whether the arguments it received
 		// exercised those fields.
 		if !isSubcommand {
 			cmd.specs = append(cmd.specs, &spec)
+			if spec.group != "" {
+				addGroup(spec.group)
+			}
 
 			var parseable bool
 			parseable, spec.boolean, spec.multiple = canParse(field.Type)
@@ -333,6 +649,17 @@ func cmdFromStruct(name string, dest path, t reflect.Type) (*command, error) {
 					t.Name(), field.Name, field.Type.String()))
 				return false
 			}
+
+			// pick a name for the value in the help text, deriving it from
+			// the type for options but not for positionals, whose names are
+			// usually more informative than their types
+			spec.placeholder = field.Tag.Get("placeholder")
+			if spec.placeholder == "" {
+				spec.placeholder = typePlaceholder(field.Type, !spec.positional)
+			}
+			if spec.placeholder == "" {
+				spec.placeholder = strings.ToUpper(spec.long)
+			}
 		}
 
 		// if this was an embedded field then we already returned true up above
@@ -367,6 +694,9 @@ func (p *Parser) Parse(args []string) error {
 			if arg == "-h" || arg == "--help" {
 				return ErrHelp
 			}
+			if arg == "--help=json" && p.config.HelpJSON {
+				return ErrHelpJSON
+			}
 			if arg == "--" {
 				break
 			}
@@ -382,7 +712,7 @@ func (p *Parser) captureEnvVars(specs []*spec, wasPresent map[*spec]bool) error
 			continue
 		}
 
-		value, found := os.LookupEnv(spec.env)
+		value, found := p.lookupEnv(spec.env)
 		if !found {
 			continue
 		}
@@ -392,25 +722,20 @@ func (p *Parser) captureEnvVars(specs []*spec, wasPresent map[*spec]bool) error
 			// variable in the case of multiple values
 			values, err := csv.NewReader(strings.NewReader(value)).Read()
 			if err != nil {
-				return fmt.Errorf(
-					"error reading a CSV string from environment variable %s with multiple values: %v",
-					spec.env,
-					err,
-				)
+				return p.envFileError(spec.env, p.errorf(MsgInvalidEnvCSV, spec.env, err))
 			}
 			if err = setSlice(p.val(spec.dest), values, !spec.separate); err != nil {
-				return fmt.Errorf(
-					"error processing environment variable %s with multiple values: %v",
-					spec.env,
-					err,
-				)
+				return p.envFileError(spec.env, p.errorf(MsgInvalidEnvValues, spec.env, err))
 			}
 		} else {
 			if err := parseValue(p.val(spec.dest), value); err != nil {
-				return fmt.Errorf("error processing environment variable %s: %v", spec.env, err)
+				return p.envFileError(spec.env, p.errorf(MsgInvalidEnvValue, spec.env, err))
 			}
 		}
 		wasPresent[spec] = true
+		if spec.deprecated != "" {
+			p.warn(p.msg(MsgDeprecatedEnvVar, spec.env, spec.deprecated))
+		}
 	}
 
 	return nil
@@ -419,19 +744,27 @@ func (p *Parser) captureEnvVars(specs []*spec, wasPresent map[*spec]bool) error
 // process goes through arguments one-by-one, parses them, and assigns the result to
 // the underlying struct field
 func (p *Parser) process(args []string) error {
-	// track the options we have seen
+	// track the options we have seen, and those given on the command line
 	wasPresent := make(map[*spec]bool)
+	onCommandLine := make(map[*spec]bool)
 
 	// union of specs for the chain of subcommands encountered so far
 	curCmd := p.cmd
 	p.lastCmd = curCmd
+	p.warnings = nil
 
 	// make a copy of the specs because we will add to this list each time we expand a subcommand
 	specs := make([]*spec, len(curCmd.specs))
 	copy(specs, curCmd.specs)
 
 	// deal with environment vars
-	err := p.captureEnvVars(specs, wasPresent)
+	if err := p.loadEnvFiles(); err != nil {
+		return err
+	}
+	if err := p.loadValueDirs(); err != nil {
+		return err
+	}
+	err := p.captureEnvironment(specs, wasPresent)
 	if err != nil {
 		return err
 	}
@@ -458,7 +791,11 @@ func (p *Parser) process(args []string) error {
 			// if we have a subcommand then make sure it is valid for the current context
 			subcmd := findSubcommand(curCmd.subcommands, arg)
 			if subcmd == nil {
-				return fmt.Errorf("invalid subcommand: %s", arg)
+				return p.errorf(MsgInvalidSubcommand, arg)
+			}
+
+			if subcmd.deprecated != "" {
+				p.warn(p.msg(MsgDeprecatedSubcommand, subcmd.name, subcmd.deprecated))
 			}
 
 			// instantiate the field to point to a new struct
@@ -469,7 +806,7 @@ func (p *Parser) process(args []string) error {
 			specs = append(specs, subcmd.specs...)
 
 			// capture environment vars for these new options
-			err := p.captureEnvVars(subcmd.specs, wasPresent)
+			err := p.captureEnvironment(subcmd.specs, wasPresent)
 			if err != nil {
 				return err
 			}
@@ -485,66 +822,34 @@ func (p *Parser) process(args []string) error {
 			return ErrHelp
 		case "--version":
 			return ErrVersion
+		case "--help=json":
+			if p.config.HelpJSON {
+				return ErrHelpJSON
+			}
 		}
 
 		// check for an equals sign, as in "--foo=bar"
-		var value string
-		opt := strings.TrimLeft(arg, "-")
-		if pos := strings.Index(opt, "="); pos != -1 {
-			value = opt[pos+1:]
-			opt = opt[:pos]
-		}
+		opt, value := splitOption(arg)
 
 		// lookup the spec for this option (note that the "specs" slice changes as
 		// we expand subcommands so it is better not to use a map)
 		spec := findOption(specs, opt)
 		if spec == nil {
-			return fmt.Errorf("unknown argument %s", arg)
-		}
-		wasPresent[spec] = true
-
-		// deal with the case of multiple values
-		if spec.multiple {
-			var values []string
-			if value == "" {
-				for i+1 < len(args) && !isFlag(args[i+1]) {
-					values = append(values, args[i+1])
-					i++
-					if spec.separate {
-						break
-					}
-				}
-			} else {
-				values = append(values, value)
-			}
-			err := setSlice(p.val(spec.dest), values, !spec.separate)
-			if err != nil {
-				return fmt.Errorf("error processing %s: %v", arg, err)
-			}
-			continue
+			return p.errorf(MsgUnknownArgument, arg)
 		}
-
-		// if it's a flag and it has no value then set the value to true
-		// use boolean because this takes account of TextUnmarshaler
-		if spec.boolean && value == "" {
-			value = "true"
-		}
-
-		// if we have something like "--foo" then the value is the next argument
-		if value == "" {
-			if i+1 == len(args) {
-				return fmt.Errorf("missing value for %s", arg)
+		if spec.deprecated != "" && !onCommandLine[spec] {
+			name := arg
+			if pos := strings.Index(name, "="); pos != -1 {
+				name = name[:pos]
 			}
-			if !nextIsNumeric(spec.typ, args[i+1]) && isFlag(args[i+1]) {
-				return fmt.Errorf("missing value for %s", arg)
-			}
-			value = args[i+1]
-			i++
+			p.warn(p.msg(MsgDeprecatedOption, name, spec.deprecated))
 		}
+		wasPresent[spec] = true
+		onCommandLine[spec] = true
 
-		err := parseValue(p.val(spec.dest), value)
+		i, err = p.setOption(spec, arg, value, args, i)
 		if err != nil {
-			return fmt.Errorf("error processing %s: %v", arg, err)
+			return err
 		}
 	}
 
@@ -556,23 +861,38 @@ func (p *Parser) process(args []string) error {
 		if len(positionals) == 0 {
 			break
 		}
+		if spec.deprecated != "" {
+			p.warn(p.msg(MsgDeprecatedOption, spec.placeholder, spec.deprecated))
+		}
 		wasPresent[spec] = true
 		if spec.multiple {
 			err := setSlice(p.val(spec.dest), positionals, true)
 			if err != nil {
-				return fmt.Errorf("error processing %s: %v", spec.long, err)
+				return p.errorf(MsgInvalidValue, spec.long, err)
 			}
 			positionals = nil
 		} else {
 			err := parseValue(p.val(spec.dest), positionals[0])
 			if err != nil {
-				return fmt.Errorf("error processing %s: %v", spec.long, err)
+				return p.errorf(MsgInvalidValue, spec.long, err)
 			}
 			positionals = positionals[1:]
 		}
 	}
 	if len(positionals) > 0 {
-		return fmt.Errorf("too many positional arguments at '%s'", positionals[0])
+		return p.errorf(MsgTooManyPositionals, positionals[0])
+	}
+
+	// files in the value directories may override the command line
+	if p.config.ValueDirPrecedence == ValueDirAboveCommandLine {
+		if err := p.captureValueDirs(specs, wasPresent); err != nil {
+			return err
+		}
+	}
+
+	// fill in options that were not set above from configuration files
+	if err := p.captureConfigFiles(curCmd, wasPresent); err != nil {
+		return err
 	}
 
 	// finally check that all the required args were provided
@@ -582,13 +902,136 @@ func (p *Parser) process(args []string) error {
 			if !spec.positional {
 				name = "--" + spec.long
 			}
-			return fmt.Errorf("%s is required", name)
+			return p.errorf(MsgRequired, name)
 		}
 	}
 
 	return nil
 }
 
+// splitOption splits an argument such as "--foo=bar" into the name of the
+// option and the value after the equals sign, if there is one
+func splitOption(arg string) (opt, value string) {
+	opt = strings.TrimLeft(arg, "-")
+	if pos := strings.Index(opt, "="); pos != -1 {
+		value = opt[pos+1:]
+		opt = opt[:pos]
+	}
+	return opt, value
+}
+
+// setOption sets an option from the command line argument args[i], taking
+// its value from after the equals sign or from the arguments that follow. It
+// returns the index of the last argument that was used.
+func (p *Parser) setOption(spec *spec, arg, value string, args []string, i int) (int, error) {
+	// deal with the case of multiple values
+	if spec.multiple {
+		var values []string
+		if value == "" {
+			for i+1 < len(args) && !isFlag(args[i+1]) {
+				values = append(values, args[i+1])
+				i++
+				if spec.separate {
+					break
+				}
+			}
+		} else {
+			values = append(values, value)
+		}
+		err := setSlice(p.val(spec.dest), values, !spec.separate)
+		if err != nil {
+			return i, p.errorf(MsgInvalidValue, arg, err)
+		}
+		return i, nil
+	}
+
+	// if it's a flag and it has no value then set the value to true
+	// use boolean because this takes account of TextUnmarshaler
+	if spec.boolean && value == "" {
+		value = "true"
+	}
+
+	// if we have something like "--foo" then the value is the next argument
+	if value == "" {
+		if i+1 == len(args) {
+			return i, p.errorf(MsgMissingValue, arg)
+		}
+		if !nextIsNumeric(spec.typ, args[i+1]) && isFlag(args[i+1]) {
+			return i, p.errorf(MsgMissingValue, arg)
+		}
+		value = args[i+1]
+		i++
+	}
+
+	err := parseValue(p.val(spec.dest), value)
+	if err != nil {
+		return i, p.errorf(MsgInvalidValue, arg, err)
+	}
+	return i, nil
+}
+
+// stdout gets the writer for help and version output
+func (p *Parser) stdout() io.Writer {
+	if p.config.Stdout != nil {
+		return p.config.Stdout
+	}
+	return os.Stdout
+}
+
+// stderr gets the writer for errors and warnings
+func (p *Parser) stderr() io.Writer {
+	if p.config.Stderr != nil {
+		return p.config.Stderr
+	}
+	return stderr
+}
+
+// exit ends the program with the given status
+func (p *Parser) exit(code int) {
+	if p.config.Exit != nil {
+		p.config.Exit(code)
+		return
+	}
+	osExit(code)
+}
+
+// lookupEnv gets the value of an environment variable as configured
+func (p *Parser) lookupEnv(name string) (string, bool) {
+	if p.config.IgnoreEnv {
+		return "", false
+	}
+	if value, found := p.lookupRealEnv(name); found {
+		return value, true
+	}
+	v, found := p.envFileVars[name]
+	return v.value, found
+}
+
+// lookupRealEnv gets the value of a variable from the environment, without
+// falling back to the variables from Config.EnvFiles
+func (p *Parser) lookupRealEnv(name string) (string, bool) {
+	if p.config.LookupEnv != nil {
+		return p.config.LookupEnv(name)
+	}
+	return os.LookupEnv(name)
+}
+
+// warn prints a warning to stderr and records it so that it can be retrieved
+// with Warnings
+func (p *Parser) warn(msg string) {
+	if p.quiet {
+		return
+	}
+	p.warnings = append(p.warnings, msg)
+	fmt.Fprintln(p.stderr(), p.msg(MsgWarningPrefix), msg)
+}
+
+// Warnings returns the deprecation warnings that were printed while processing
+// the most recent command line
+func (p *Parser) Warnings() []string {
+	return p.warnings
+}
+
 func nextIsNumeric(t reflect.Type, s string) bool {
 	switch t.Kind() {
 	case reflect.Ptr:
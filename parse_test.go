@@ -1,6 +1,7 @@
 package arg
 
 import (
+	"bytes"
 	"net"
 	"net/mail"
 	"os"
@@ -552,6 +553,54 @@ func TestMustParse(t *testing.T) {
 	assert.NotNil(t, parser)
 }
 
+func TestParserMustParse(t *testing.T) {
+	var args struct {
+		versioned
+		Foo string `arg:"required"`
+	}
+	var stdout, stderr bytes.Buffer
+	var codes []int
+	p, err := NewParser(Config{
+		Program:       "example",
+		Stdout:        &stdout,
+		Stderr:        &stderr,
+		Exit:          func(code int) { codes = append(codes, code) },
+		UsageExitCode: 2,
+		HelpExitCode:  3,
+	}, &args)
+	require.NoError(t, err)
+
+	p.MustParse([]string{"--foo", "bar"})
+	assert.Equal(t, "bar", args.Foo)
+	assert.Empty(t, codes)
+
+	p.MustParse(nil)
+	assert.Equal(t, "example 3.2.1\nUsage: example --foo FOO\nerror: --foo is required\n", stderr.String())
+	assert.Empty(t, stdout.String())
+
+	p.MustParse([]string{"--version"})
+	assert.Equal(t, "example 3.2.1\n", stdout.String())
+
+	stdout.Reset()
+	p.MustParse([]string{"--help"})
+	assert.Contains(t, stdout.String(), "Usage: example --foo FOO\n")
+	assert.Equal(t, []int{2, 3, 3}, codes)
+}
+
+func TestParserMustParseDefaultExitCode(t *testing.T) {
+	var args struct {
+		Foo string `arg:"required"`
+	}
+	var stderr bytes.Buffer
+	var code int
+	p, err := NewParser(Config{Stderr: &stderr, Exit: func(c int) { code = c }}, &args)
+	require.NoError(t, err)
+
+	p.MustParse(nil)
+	assert.Equal(t, -1, code)
+	assert.Contains(t, stderr.String(), "error: --foo is required")
+}
+
 func TestEnvironmentVariable(t *testing.T) {
 	var args struct {
 		Foo string `arg:"env"`
@@ -581,6 +630,87 @@ func TestEnvironmentVariableOverrideName(t *testing.T) {
 	assert.Equal(t, "bar", args.Foo)
 }
 
+func TestEnvironmentVariablePrefix(t *testing.T) {
+	type pushCmd struct {
+		Remote string `arg:"env"`
+		Branch string `arg:"env:GIT_BRANCH"`
+	}
+	var args struct {
+		Workers int      `arg:"env"`
+		Push    *pushCmd `arg:"subcommand"`
+	}
+	setenv(t, "MYAPP_WORKERS", "4")
+	setenv(t, "MYAPP_PUSH_REMOTE", "origin")
+	setenv(t, "GIT_BRANCH", "main")
+	defer os.Unsetenv("MYAPP_WORKERS")
+	defer os.Unsetenv("MYAPP_PUSH_REMOTE")
+	defer os.Unsetenv("GIT_BRANCH")
+
+	p, err := NewParser(Config{EnvPrefix: "MYAPP_"}, &args)
+	require.NoError(t, err)
+	err = p.Parse([]string{"push"})
+	require.NoError(t, err)
+	assert.Equal(t, 4, args.Workers)
+	require.NotNil(t, args.Push)
+	assert.Equal(t, "origin", args.Push.Remote)
+	assert.Equal(t, "main", args.Push.Branch)
+}
+
+func TestEnvironmentVariableAllOptions(t *testing.T) {
+	var args struct {
+		Workers int
+		Input   string `arg:"positional"`
+	}
+	setenv(t, "MYAPP_WORKERS", "4")
+	setenv(t, "MYAPP_INPUT", "ignored")
+	defer os.Unsetenv("MYAPP_WORKERS")
+	defer os.Unsetenv("MYAPP_INPUT")
+
+	p, err := NewParser(Config{EnvPrefix: "MYAPP_", EnvAllOptions: true}, &args)
+	require.NoError(t, err)
+	err = p.Parse(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, args.Workers)
+	assert.Equal(t, "", args.Input)
+}
+
+func TestEnvironmentVariableLookup(t *testing.T) {
+	t.Parallel()
+	env := map[string]string{"WORKERS": "4", "INPUT": "a,b"}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	var args struct {
+		Workers int      `arg:"env"`
+		Input   []string `arg:"env"`
+		Output  string   `arg:"env"`
+	}
+	p, err := NewParser(Config{LookupEnv: lookup}, &args)
+	require.NoError(t, err)
+	err = p.Parse(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, args.Workers)
+	assert.Equal(t, []string{"a", "b"}, args.Input)
+	assert.Equal(t, "", args.Output)
+}
+
+func TestEnvironmentVariableIgnored(t *testing.T) {
+	t.Parallel()
+	lookup := func(name string) (string, bool) {
+		return "4", true
+	}
+
+	var args struct {
+		Workers int `arg:"env,required"`
+	}
+	p, err := NewParser(Config{LookupEnv: lookup, IgnoreEnv: true}, &args)
+	require.NoError(t, err)
+	err = p.Parse(nil)
+	assert.EqualError(t, err, "--workers is required")
+}
+
 func TestEnvironmentVariableOverrideArgument(t *testing.T) {
 	var args struct {
 		Foo string `arg:"env"`
@@ -1045,3 +1175,42 @@ func TestVersion(t *testing.T) {
 	assert.Equal(t, ErrVersion, err)
 
 }
+
+func TestDeprecatedOption(t *testing.T) {
+	var args struct {
+		Out     string `deprecated:"use --output instead"`
+		Workers int    `arg:"env" deprecated:"use --jobs instead"`
+		Output  string
+	}
+	setenv(t, "WORKERS", "4")
+	defer os.Unsetenv("WORKERS")
+	p, err := pparse("--out=x --out y --output z", &args)
+	require.NoError(t, err)
+	assert.Equal(t, "y", args.Out)
+	assert.Equal(t, 4, args.Workers)
+	assert.Equal(t, []string{
+		"environment variable WORKERS is deprecated: use --jobs instead",
+		"--out is deprecated: use --output instead",
+	}, p.Warnings())
+
+	p, err = pparse("--output z", &args)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"environment variable WORKERS is deprecated: use --jobs instead",
+	}, p.Warnings())
+}
+
+func TestDeprecatedOptionFromEnvAndCommandLine(t *testing.T) {
+	var args struct {
+		Workers int `arg:"env" deprecated:"use --jobs instead"`
+	}
+	setenv(t, "WORKERS", "4")
+	defer os.Unsetenv("WORKERS")
+	p, err := pparse("--workers 8", &args)
+	require.NoError(t, err)
+	assert.Equal(t, 8, args.Workers)
+	assert.Equal(t, []string{
+		"environment variable WORKERS is deprecated: use --jobs instead",
+		"--workers is deprecated: use --jobs instead",
+	}, p.Warnings())
+}
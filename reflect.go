@@ -3,12 +3,14 @@ package arg
 import (
 	"encoding"
 	"reflect"
+	"time"
 
 	scalar "github.com/alexflint/go-scalar"
 )
 
 var textUnmarshalerType = reflect.TypeOf([]encoding.TextUnmarshaler{}).Elem()
 var argUnmarshalerType = reflect.TypeOf([]ArgUnmarshaler{}).Elem()
+var durationType = reflect.TypeOf(time.Duration(0))
 
 func canParseWrapped(t reflect.Type) bool {
 	if t.Implements(argUnmarshalerType) || reflect.PtrTo(t).Implements(argUnmarshalerType) {
@@ -70,3 +72,43 @@ func isBoolean(t reflect.Type) bool {
 		return false
 	}
 }
+
+// typePlaceholder returns the name for a value of the given type in the help
+// text, as chosen by the ArgPlaceholder interface or, if fromKind is true,
+// derived from the kind of the type. It returns an empty string if neither
+// applies.
+func typePlaceholder(t reflect.Type, fromKind bool) string {
+	// Look inside pointer and slice types, as well as slices of pointers,
+	// stopping at types with their own parsing logic
+	for {
+		// a pointer to a new value has both the value and pointer methods
+		base := t
+		if base.Kind() == reflect.Ptr {
+			base = base.Elem()
+		}
+		if v, ok := reflect.New(base).Interface().(ArgPlaceholder); ok {
+			return v.Placeholder()
+		}
+		if t.Implements(textUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType) ||
+			t.Implements(argUnmarshalerType) || reflect.PtrTo(t).Implements(argUnmarshalerType) {
+			return ""
+		}
+		if t.Kind() != reflect.Ptr && t.Kind() != reflect.Slice {
+			break
+		}
+		t = t.Elem()
+	}
+
+	if !fromKind {
+		return ""
+	}
+	if t == durationType {
+		return "DURATION"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "N"
+	}
+	return ""
+}
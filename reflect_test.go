@@ -1,8 +1,10 @@
 package arg
 
 import (
+	"net"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -53,3 +55,21 @@ func TestCanParseTextUnmarshaler(t *testing.T) {
 	assertCanParse(t, reflect.TypeOf(su), true, false, true)
 	assertCanParse(t, reflect.TypeOf(&su), true, false, true)
 }
+
+func TestTypePlaceholder(t *testing.T) {
+	var i int
+	var u []uint8
+	var d time.Duration
+	var ip net.IP
+	var s string
+	var l []*compressionLevel
+
+	assert.Equal(t, "N", typePlaceholder(reflect.TypeOf(i), true))
+	assert.Equal(t, "N", typePlaceholder(reflect.TypeOf(&i), true))
+	assert.Equal(t, "N", typePlaceholder(reflect.TypeOf(u), true))
+	assert.Equal(t, "", typePlaceholder(reflect.TypeOf(i), false))
+	assert.Equal(t, "DURATION", typePlaceholder(reflect.TypeOf(d), true))
+	assert.Equal(t, "", typePlaceholder(reflect.TypeOf(ip), true))
+	assert.Equal(t, "", typePlaceholder(reflect.TypeOf(s), true))
+	assert.Equal(t, "1-9", typePlaceholder(reflect.TypeOf(l), false))
+}
@@ -0,0 +1,193 @@
+package arg
+
+import (
+	"io"
+	"strings"
+	"text/template"
+)
+
+// Help is the data from which usage and help text are rendered. It is passed
+// to a HelpRenderer, and is the data model for templates used with
+// TemplateRenderer.
+type Help struct {
+	Command       *Command     // the command for which help was requested
+	Ancestors     []*Command   // the ancestors of Command, starting with the top-level command
+	Usage         string       // the usage line, without the "Usage:" prefix
+	Version       string       // from the Versioned interface of Command or its nearest ancestor that has one
+	Description   string       // from the Described interface of Command, or its help tag
+	Positionals   []*Option    // positional arguments of Command
+	Options       []*Option    // options of Command that are not in a group, followed by --help and --version
+	Groups        []*HelpGroup // groups of options of Command, in display order
+	GlobalOptions []*Option    // options inherited from Ancestors
+	Subcommands   []*Command   // subcommands of Command
+	Environment   []*Option    // options of Command, its ancestors and subcommands that can be set from the environment
+	Examples      []Example    // from the Exampled interface
+	Epilogue      string       // from the Epilogued interface
+}
+
+// HelpGroup is a named section of options in the help text
+type HelpGroup struct {
+	Name        string    // the name from the group tag
+	Description string    // from the Described interface of an embedded struct
+	Options     []*Option // the options in the group
+}
+
+// HelpRenderer is the interface for replacing the built-in layout of usage
+// and help text. Hidden options and subcommands are never passed to the
+// renderer.
+type HelpRenderer interface {
+	// RenderUsage writes the short usage message shown when there is an error
+	RenderUsage(w io.Writer, h *Help) error
+	// RenderHelp writes the full help text shown for --help
+	RenderHelp(w io.Writer, h *Help) error
+}
+
+// TemplateRenderer is a HelpRenderer that executes text/template templates
+// with a *Help as the data. If either template is nil then the built-in layout
+// is used for that part; the built-in help layout still uses the Usage
+// template for its usage line. The functions from HelpFuncs are available to
+// templates that were parsed with them.
+type TemplateRenderer struct {
+	Usage *template.Template
+	Help  *template.Template
+}
+
+// RenderUsage executes the usage template
+func (r TemplateRenderer) RenderUsage(w io.Writer, h *Help) error {
+	if r.Usage == nil {
+		return defaultRenderer{p: h.Command.p}.RenderUsage(w, h)
+	}
+	return r.Usage.Execute(w, h)
+}
+
+// RenderHelp executes the help template
+func (r TemplateRenderer) RenderHelp(w io.Writer, h *Help) error {
+	if r.Help == nil {
+		return defaultRenderer{p: h.Command.p, usage: r.RenderUsage}.RenderHelp(w, h)
+	}
+	return r.Help.Execute(w, h)
+}
+
+// HelpFuncs returns functions that are useful in help templates:
+//
+//	left OPTION         the option as it appears in the left column, e.g. "--workers N, -w N"
+//	pad WIDTH TEXT      the text padded with spaces to the given width
+//	wrap WIDTH TEXT     the text wrapped to the given width
+//	indent WIDTH TEXT   the text with each line after the first indented by the given width
+func HelpFuncs() template.FuncMap {
+	return template.FuncMap{
+		"left": func(o *Option) string {
+			return optionLeft(o.spec)
+		},
+		"pad": func(width int, s string) string {
+			if n := stringWidth(s); n < width {
+				return s + strings.Repeat(" ", width-n)
+			}
+			return s
+		},
+		"wrap": func(width int, s string) string {
+			return strings.Join(wrapText(s, width), "\n")
+		},
+		"indent": func(width int, s string) string {
+			return strings.Replace(s, "\n", "\n"+strings.Repeat(" ", width), -1)
+		},
+	}
+}
+
+// renderer gets the configured help renderer, or the built-in one
+func (p *Parser) renderer() HelpRenderer {
+	if p.config.HelpRenderer != nil {
+		return p.config.HelpRenderer
+	}
+	return defaultRenderer{p: p}
+}
+
+// helpFor collects the data for the usage and help text of a command
+func (p *Parser) helpFor(cmd *command) *Help {
+	h := Help{
+		Command:     &Command{p: p, cmd: cmd},
+		Usage:       p.synopsisForCommand(cmd),
+		Version:     versionOf(cmd),
+		Description: commandDescription(cmd),
+		Examples:    cmd.examples,
+		Epilogue:    cmd.epilogue,
+	}
+	for ancestor := cmd.parent; ancestor != nil; ancestor = ancestor.parent {
+		h.Ancestors = append([]*Command{{p: p, cmd: ancestor}}, h.Ancestors...)
+	}
+
+	positionals, options := visibleSpecs(cmd)
+	for _, spec := range positionals {
+		h.Positionals = append(h.Positionals, &Option{p: p, spec: spec})
+	}
+	for _, spec := range options {
+		if spec.group == "" {
+			h.Options = append(h.Options, &Option{p: p, spec: spec})
+		}
+	}
+
+	// add the built in options
+	h.Options = append(h.Options, &Option{p: p, spec: &spec{
+		boolean: true,
+		long:    "help",
+		short:   "h",
+		help:    p.msg(MsgHelpOption),
+	}})
+	if h.Version != "" {
+		h.Options = append(h.Options, &Option{p: p, spec: &spec{
+			boolean: true,
+			long:    "version",
+			help:    p.msg(MsgVersionOption),
+		}})
+	}
+
+	for _, g := range p.orderGroups(cmd.groups) {
+		hg := HelpGroup{Name: g.name, Description: g.description}
+		for _, spec := range options {
+			if spec.group == g.name {
+				hg.Options = append(hg.Options, &Option{p: p, spec: spec})
+			}
+		}
+		if len(hg.Options) > 0 {
+			h.Groups = append(h.Groups, &hg)
+		}
+	}
+
+	for _, ancestor := range h.Ancestors {
+		_, specs := visibleSpecs(ancestor.cmd)
+		for _, spec := range specs {
+			h.GlobalOptions = append(h.GlobalOptions, &Option{p: p, spec: spec})
+		}
+	}
+
+	for _, subcmd := range visibleSubcommands(cmd) {
+		h.Subcommands = append(h.Subcommands, &Command{p: p, cmd: subcmd})
+	}
+
+	// collect environment variables from the ancestors, then from this
+	// command and its subcommands, listing each variable once
+	seen := make(map[string]bool)
+	addEnv := func(c *command) {
+		for _, spec := range c.specs {
+			if spec.env != "" && !spec.hidden && !spec.secret && !seen[spec.env] {
+				seen[spec.env] = true
+				h.Environment = append(h.Environment, &Option{p: p, spec: spec})
+			}
+		}
+	}
+	for _, ancestor := range h.Ancestors {
+		addEnv(ancestor.cmd)
+	}
+	walkCommands(cmd, addEnv)
+	return &h
+}
+
+// allOptions gets every option in the help text, including those in groups
+// and those inherited from ancestors
+func (h *Help) allOptions() []*Option {
+	out := append([]*Option{}, h.Options...)
+	for _, g := range h.Groups {
+		out = append(out, g.Options...)
+	}
+	return append(out, h.GlobalOptions...)
+}
@@ -0,0 +1,60 @@
+package arg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ExitCoder is the interface implemented by errors that decide the exit status
+// of the program when they are returned to Run
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// UsageError is an error in the command line arguments that Parse could not
+// detect, such as a combination of options that is not allowed. When returned
+// to Run it is printed with the usage of the selected command.
+type UsageError struct {
+	Message string
+}
+
+// Error returns the message
+func (e *UsageError) Error() string {
+	return e.Message
+}
+
+// ExitCode returns 2, the conventional exit status for usage errors
+func (e *UsageError) ExitCode() int {
+	return 2
+}
+
+// Run processes command line arguments like MustParse, then calls run and
+// exits if it returns an error. Errors in the command line, including a
+// *UsageError returned by run, are printed after the usage of the selected
+// command and exit with status 2. Other errors are printed without the usage
+// and exit with the status from their ExitCode method if they implement
+// ExitCoder, or with status 1 otherwise. Wrapped errors are recognized as well.
+func (p *Parser) Run(args []string, run func() error) {
+	if !p.parseOrExit(args, 2) {
+		return
+	}
+
+	err := run()
+	if err == nil {
+		return
+	}
+	var usageErr *UsageError
+	if errors.As(err, &usageErr) {
+		p.failWithCommand(err.Error(), p.lastCmd, usageErr.ExitCode())
+		return
+	}
+
+	w := p.stderr()
+	fmt.Fprintln(w, p.styleFor(w).err(p.msg(MsgErrorPrefix)), err)
+	code := 1
+	var coder ExitCoder
+	if errors.As(err, &coder) {
+		code = coder.ExitCode()
+	}
+	p.exit(code)
+}
@@ -0,0 +1,123 @@
+package arg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type exitError struct{}
+
+func (exitError) Error() string {
+	return "remote hung up"
+}
+
+func (exitError) ExitCode() int {
+	return 7
+}
+
+func newRunParser(t *testing.T, dest interface{}, config Config) (*Parser, *bytes.Buffer, *int) {
+	var stderr bytes.Buffer
+	code := -100
+	config.Program = "example"
+	config.Stderr = &stderr
+	config.Exit = func(c int) { code = c }
+	p, err := NewParser(config, dest)
+	require.NoError(t, err)
+	return p, &stderr, &code
+}
+
+func TestRun(t *testing.T) {
+	type pushCmd struct {
+		Remote string `arg:"positional"`
+	}
+	var args struct {
+		Push *pushCmd `arg:"subcommand"`
+	}
+
+	var ran bool
+	p, stderr, code := newRunParser(t, &args, Config{})
+	p.Run([]string{"push", "origin"}, func() error {
+		ran = true
+		return nil
+	})
+	assert.True(t, ran)
+	assert.Equal(t, -100, *code)
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunWithUsageErrors(t *testing.T) {
+	type pushCmd struct {
+		Remote string `arg:"positional"`
+	}
+	var args struct {
+		Push *pushCmd `arg:"subcommand"`
+	}
+
+	// errors from Parse
+	p, stderr, code := newRunParser(t, &args, Config{})
+	p.Run([]string{"push", "--force"}, func() error {
+		t.Error("run should not be called")
+		return nil
+	})
+	assert.Equal(t, 2, *code)
+	assert.Equal(t, "Usage: example push REMOTE\nerror: unknown argument --force\n", stderr.String())
+
+	// errors from the command
+	p, stderr, code = newRunParser(t, &args, Config{})
+	p.Run([]string{"push"}, func() error {
+		return &UsageError{Message: "a remote is required to push"}
+	})
+	assert.Equal(t, 2, *code)
+	assert.Equal(t, "Usage: example push REMOTE\nerror: a remote is required to push\n", stderr.String())
+
+	// configured exit status
+	p, _, code = newRunParser(t, &args, Config{UsageExitCode: 64})
+	p.Run([]string{"pull"}, func() error { return nil })
+	assert.Equal(t, 64, *code)
+}
+
+func TestRunWithRuntimeErrors(t *testing.T) {
+	var args struct{}
+
+	p, stderr, code := newRunParser(t, &args, Config{})
+	p.Run(nil, func() error {
+		return errors.New("disk full")
+	})
+	assert.Equal(t, 1, *code)
+	assert.Equal(t, "error: disk full\n", stderr.String())
+
+	p, stderr, code = newRunParser(t, &args, Config{})
+	p.Run(nil, func() error {
+		return exitError{}
+	})
+	assert.Equal(t, 7, *code)
+	assert.Equal(t, "error: remote hung up\n", stderr.String())
+}
+
+func TestRunWithWrappedErrors(t *testing.T) {
+	type pushCmd struct {
+		Remote string `arg:"positional"`
+	}
+	var args struct {
+		Push *pushCmd `arg:"subcommand"`
+	}
+
+	p, stderr, code := newRunParser(t, &args, Config{})
+	p.Run([]string{"push"}, func() error {
+		return fmt.Errorf("push: %w", &UsageError{Message: "a remote is required"})
+	})
+	assert.Equal(t, 2, *code)
+	assert.Equal(t, "Usage: example push REMOTE\nerror: push: a remote is required\n", stderr.String())
+
+	p, stderr, code = newRunParser(t, &args, Config{})
+	p.Run(nil, func() error {
+		return fmt.Errorf("sync failed: %w", exitError{})
+	})
+	assert.Equal(t, 7, *code)
+	assert.Equal(t, "error: sync failed: remote hung up\n", stderr.String())
+}
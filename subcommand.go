@@ -35,3 +35,22 @@ func (p *Parser) SubcommandNames() []string {
 	}
 	return out
 }
+
+// walkCommands calls a function for a command and each of its descendants, in
+// depth-first order, skipping hidden subcommands and their descendants
+func walkCommands(cmd *command, visit func(*command)) {
+	visit(cmd)
+	for _, subcmd := range visibleSubcommands(cmd) {
+		walkCommands(subcmd, visit)
+	}
+}
+
+// commandPath gets the names of a command and all its ancestors, starting at
+// the top-level command
+func commandPath(cmd *command) []string {
+	var names []string
+	for ; cmd != nil; cmd = cmd.parent {
+		names = append([]string{cmd.name}, names...)
+	}
+	return names
+}
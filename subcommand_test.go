@@ -353,3 +353,15 @@ func TestSubcommandsWithMultiplePositionals(t *testing.T) {
 		assert.Equal(t, 5, args.Limit)
 	}
 }
+
+func TestDeprecatedSubcommand(t *testing.T) {
+	type fetchCmd struct {
+	}
+	var args struct {
+		Fetch *fetchCmd `arg:"subcommand" deprecated:"use get instead"`
+	}
+	p, err := pparse("fetch", &args)
+	require.NoError(t, err)
+	assert.NotNil(t, args.Fetch)
+	assert.Equal(t, []string{"subcommand fetch is deprecated: use get instead"}, p.Warnings())
+}
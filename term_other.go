@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package arg
+
+import "os"
+
+// terminalSize always reports that the file is not a terminal on platforms
+// where terminal detection is not supported
+func terminalSize(f *os.File) (int, bool) {
+	return 0, false
+}
@@ -6,10 +6,11 @@ import (
 	"io"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
-// the width of the left column
+// the width of the left column when help text is not wrapped
 const colWidth = 25
 
 // to allow monkey patching in tests
@@ -17,14 +18,19 @@ var stderr = os.Stderr
 
 // Fail prints usage information to stderr and exits with non-zero status
 func (p *Parser) Fail(msg string) {
-	p.failWithCommand(msg, p.cmd)
+	p.failWithCommand(msg, p.cmd, -1)
 }
 
-// failWithCommand prints usage information for the given subcommand to stderr and exits with non-zero status
-func (p *Parser) failWithCommand(msg string, cmd *command) {
-	p.writeUsageForCommand(stderr, cmd)
-	fmt.Fprintln(stderr, "error:", msg)
-	osExit(-1)
+// failWithCommand prints usage information for the given subcommand to stderr
+// and exits with the given status, or with Config.UsageExitCode if it is set
+func (p *Parser) failWithCommand(msg string, cmd *command, code int) {
+	w := p.stderr()
+	p.writeUsageForCommand(w, cmd)
+	fmt.Fprintln(w, p.styleFor(w).err(p.msg(MsgErrorPrefix)), msg)
+	if p.config.UsageExitCode != 0 {
+		code = p.config.UsageExitCode
+	}
+	p.exit(code)
 }
 
 // WriteUsage writes usage information to the given writer
@@ -34,79 +40,248 @@ func (p *Parser) WriteUsage(w io.Writer) {
 
 // writeUsageForCommand writes usage information for the given subcommand
 func (p *Parser) writeUsageForCommand(w io.Writer, cmd *command) {
-	var positionals, options []*spec
-	for _, spec := range cmd.specs {
-		if spec.positional {
-			positionals = append(positionals, spec)
-		} else {
-			options = append(options, spec)
-		}
+	if err := p.renderer().RenderUsage(w, p.helpFor(cmd)); err != nil {
+		p.renderFailed(err)
 	}
+}
+
+// renderFailed reports an error from a help renderer on stderr, so that it
+// does not end up in the help text itself
+func (p *Parser) renderFailed(err error) {
+	w := p.stderr()
+	fmt.Fprintln(w, p.styleFor(w).err(p.msg(MsgErrorPrefix)), p.msg(MsgRenderError, err))
+}
 
-	if p.version != "" {
-		fmt.Fprintln(w, p.version)
+// defaultRenderer produces the built-in help layout
+type defaultRenderer struct {
+	p     *Parser
+	usage func(io.Writer, *Help) error // writes the usage line in the help text; RenderUsage if nil
+}
+
+// RenderUsage writes the version and usage line, wrapped to the help width
+func (r defaultRenderer) RenderUsage(w io.Writer, h *Help) error {
+	if h.Version != "" {
+		fmt.Fprintln(w, h.Version)
 	}
 
-	// make a list of ancestor commands so that we print with full context
-	var ancestors []string
-	ancestor := cmd
-	for ancestor != nil {
-		ancestors = append(ancestors, ancestor.name)
-		ancestor = ancestor.parent
+	st := r.p.styleFor(w)
+	parts := r.p.usageParts(h.Command.cmd, st)
+	width := r.p.helpWidth(w)
+	if width == 0 {
+		fmt.Fprintln(w, st.header(r.p.msg(MsgUsage))+" "+strings.Join(parts, " "))
+		return nil
 	}
 
-	// print the beginning of the usage string
-	fmt.Fprint(w, "Usage:")
-	for i := len(ancestors) - 1; i >= 0; i-- {
-		fmt.Fprint(w, " "+ancestors[i])
+	// wrap everything after the program name, aligning continuation lines
+	// with the first component after the program name
+	prefix := st.header(r.p.msg(MsgUsage)) + " " + parts[0]
+	if len(parts) == 1 {
+		fmt.Fprintln(w, prefix)
+		return nil
+	}
+	indent := stringWidth(prefix) + 1
+	if indent > width/2 {
+		indent = 4
 	}
+	lines := wrapWords(parts[1:], width-stringWidth(prefix)-1, width-indent)
+	fmt.Fprintln(w, prefix+" "+strings.Join(lines, "\n"+strings.Repeat(" ", indent)))
+	return nil
+}
 
-	// write the option component of the usage message
-	for _, spec := range options {
-		// prefix with a space
-		fmt.Fprint(w, " ")
-		if !spec.required {
-			fmt.Fprint(w, "[")
-		}
-		fmt.Fprint(w, synopsis(spec, "--"+spec.long))
-		if !spec.required {
-			fmt.Fprint(w, "]")
-		}
+// synopsisForCommand gets the usage line for the given subcommand, without
+// the "Usage:" prefix
+func (p *Parser) synopsisForCommand(cmd *command) string {
+	return strings.Join(p.usageParts(cmd, style{}), " ")
+}
+
+// usageParts gets the components of the usage line for the given subcommand:
+// the names of the command and its ancestors, each followed by its options
+// (since the options of ancestors are also accepted after a subcommand),
+// followed by the positionals
+func (p *Parser) usageParts(cmd *command, st style) []string {
+	var chain []*command
+	for c := cmd; c != nil; c = c.parent {
+		chain = append([]*command{c}, chain...)
 	}
 
+	var parts []string
+	for _, c := range chain {
+		parts = append(parts, c.name)
+		parts = append(parts, p.optionUsageParts(c, st)...)
+	}
+
+	positionals, _ := visibleSpecs(cmd)
+
 	// write the positional component of the usage message
 	for _, spec := range positionals {
-		// prefix with a space
-		fmt.Fprint(w, " ")
-		up := strings.ToUpper(spec.long)
+		up := st.placeholder(spec.placeholder)
 		if spec.multiple {
-			if !spec.required {
-				fmt.Fprint(w, "[")
-			}
-			fmt.Fprintf(w, "%s [%s ...]", up, up)
-			if !spec.required {
-				fmt.Fprint(w, "]")
+			if spec.required {
+				parts = append(parts, fmt.Sprintf("%s [%s ...]", up, up))
+			} else {
+				parts = append(parts, fmt.Sprintf("[%s [%s ...]]", up, up))
 			}
 		} else {
-			fmt.Fprint(w, up)
+			parts = append(parts, up)
 		}
 	}
-	fmt.Fprint(w, "\n")
+	return parts
+}
+
+// optionUsageParts gets the components of the usage line for the options of
+// the given command
+func (p *Parser) optionUsageParts(cmd *command, st style) []string {
+	_, options := visibleSpecs(cmd)
+
+	// collapse the optional options if there are too many of them
+	var parts []string
+	var optional int
+	for _, spec := range options {
+		if !spec.required {
+			optional++
+		}
+	}
+	compact := p.config.CompactUsage > 0 && optional > p.config.CompactUsage
+	if compact {
+		parts = append(parts, p.msg(MsgCompactOptions))
+	}
+
+	for _, spec := range options {
+		if compact && !spec.required {
+			continue
+		}
+		if spec.required {
+			parts = append(parts, st.synopsis(spec, "--"+spec.long))
+		} else {
+			parts = append(parts, "["+st.synopsis(spec, "--"+spec.long)+"]")
+		}
+	}
+	return parts
 }
 
-func printTwoCols(w io.Writer, left, help string, defaultVal *string) {
+// helpWidth gets the width to which help text written to w should be wrapped,
+// or zero if it should not be wrapped
+func (p *Parser) helpWidth(w io.Writer) int {
+	if p.config.HelpWidth != 0 {
+		if p.config.HelpWidth < 0 {
+			return 0
+		}
+		return p.config.HelpWidth
+	}
+
+	columns, _ := p.lookupEnv("COLUMNS")
+	n, err := strconv.Atoi(columns)
+	hasColumns := err == nil && n > 0
+	if hasColumns && p.config.ColumnsWhenPiped {
+		return n
+	}
+
+	// only wrap when writing directly to a terminal
+	f, ok := w.(*os.File)
+	if !ok {
+		return 0
+	}
+	cols, ok := terminalSize(f)
+	if !ok {
+		return 0
+	}
+	if hasColumns {
+		return n
+	}
+	return cols
+}
+
+// layout describes how two-column help text is arranged
+type layout struct {
+	width int // the total width to wrap to, or zero to disable wrapping
+	col   int // the width of the left column
+}
+
+// layoutFor computes the layout for help text written to w, given the
+// contents of the left column
+func (p *Parser) layoutFor(w io.Writer, lefts []string) layout {
+	width := p.helpWidth(w)
+	if width == 0 {
+		return layout{col: colWidth}
+	}
+
+	// make the left column wide enough for all entries except those so long
+	// that they would squeeze the help text, which are placed on their own line
+	maxCol := width * 2 / 5
+	var col int
+	for _, left := range lefts {
+		if n := stringWidth("  "+left) + 3; n <= maxCol && n > col {
+			col = n
+		}
+	}
+	if col == 0 {
+		col = maxCol
+	}
+	return layout{width: width, col: col}
+}
+
+// wrap writes a paragraph of text, wrapped to the layout width
+func (l layout) wrap(w io.Writer, text string) {
+	if l.width == 0 {
+		fmt.Fprintln(w, text)
+		return
+	}
+	fmt.Fprintln(w, strings.Join(wrapText(text, l.width), "\n"))
+}
+
+// indent writes a paragraph of text indented by the given number of spaces,
+// wrapped to the layout width
+func (l layout) indent(w io.Writer, n int, text string) {
+	prefix := strings.Repeat(" ", n)
+	if l.width == 0 {
+		fmt.Fprintln(w, prefix+strings.Replace(text, "\n", "\n"+prefix, -1))
+		return
+	}
+	width := l.width - n
+	if width < 10 {
+		width = 10
+	}
+	fmt.Fprintln(w, prefix+strings.Join(wrapText(text, width), "\n"+prefix))
+}
+
+func (l layout) printTwoCols(w io.Writer, left, help string, notes []string) {
 	lhs := "  " + left
 	fmt.Fprint(w, lhs)
+
+	if l.width == 0 {
+		if help != "" {
+			if stringWidth(lhs)+2 < l.col {
+				fmt.Fprint(w, strings.Repeat(" ", l.col-stringWidth(lhs)))
+			} else {
+				fmt.Fprint(w, "\n"+strings.Repeat(" ", l.col))
+			}
+			fmt.Fprint(w, help)
+		}
+		for _, note := range notes {
+			fmt.Fprintf(w, " [%s]", note)
+		}
+		fmt.Fprint(w, "\n")
+		return
+	}
+
+	for _, note := range notes {
+		if help != "" {
+			help += " "
+		}
+		help += "[" + note + "]"
+	}
 	if help != "" {
-		if len(lhs)+2 < colWidth {
-			fmt.Fprint(w, strings.Repeat(" ", colWidth-len(lhs)))
+		indent := strings.Repeat(" ", l.col)
+		if stringWidth(lhs)+2 < l.col {
+			fmt.Fprint(w, strings.Repeat(" ", l.col-stringWidth(lhs)))
 		} else {
-			fmt.Fprint(w, "\n"+strings.Repeat(" ", colWidth))
+			fmt.Fprint(w, "\n"+indent)
 		}
-		fmt.Fprint(w, help)
-	}
-	if defaultVal != nil {
-		fmt.Fprintf(w, " [default: %s]", *defaultVal)
+		helpWidth := l.width - l.col
+		if helpWidth < 10 {
+			helpWidth = 10
+		}
+		fmt.Fprint(w, strings.Join(wrapText(help, helpWidth), "\n"+indent))
 	}
 	fmt.Fprint(w, "\n")
 }
@@ -118,64 +293,217 @@ func (p *Parser) WriteHelp(w io.Writer) {
 
 // writeHelp writes the usage string for the given subcommand
 func (p *Parser) writeHelpForCommand(w io.Writer, cmd *command) {
-	var positionals, options []*spec
-	for _, spec := range cmd.specs {
-		if spec.positional {
-			positionals = append(positionals, spec)
-		} else {
-			options = append(options, spec)
-		}
+	if err := p.renderer().RenderHelp(w, p.helpFor(cmd)); err != nil {
+		p.renderFailed(err)
 	}
+}
 
-	if p.description != "" {
-		fmt.Fprintln(w, p.description)
+// RenderHelp writes the description, usage line, and a two-column list of
+// arguments, options and subcommands
+func (r defaultRenderer) RenderHelp(w io.Writer, h *Help) error {
+	st := r.p.styleFor(w)
+
+	// compute the layout from everything that goes in the left column
+	var lefts []string
+	for _, opt := range h.Positionals {
+		lefts = append(lefts, st.placeholder(opt.spec.placeholder))
+	}
+	for _, opt := range h.allOptions() {
+		lefts = append(lefts, st.optionLeft(opt.spec))
+	}
+	for _, subcmd := range h.Subcommands {
+		lefts = append(lefts, st.name(subcmd.cmd.name))
+	}
+	l := r.p.layoutFor(w, lefts)
+
+	if h.Description != "" {
+		l.wrap(w, h.Description)
+	}
+	renderUsage := r.RenderUsage
+	if r.usage != nil {
+		renderUsage = r.usage
+	}
+	if err := renderUsage(w, h); err != nil {
+		return err
 	}
-	p.writeUsageForCommand(w, cmd)
 
 	// write the list of positionals
-	if len(positionals) > 0 {
-		fmt.Fprint(w, "\nPositional arguments:\n")
-		for _, spec := range positionals {
-			printTwoCols(w, strings.ToUpper(spec.long), spec.help, nil)
+	if len(h.Positionals) > 0 {
+		fmt.Fprint(w, "\n"+st.header(r.p.msg(MsgPositionalArguments))+"\n")
+		for _, opt := range h.Positionals {
+			l.printTwoCols(w, st.placeholder(opt.spec.placeholder), r.p.specHelp(opt.spec), r.p.specNotes(opt.spec))
 		}
 	}
 
-	// write the list of options
-	fmt.Fprint(w, "\nOptions:\n")
-	for _, spec := range options {
-		p.printOption(w, spec)
+	// write the list of options that are not in any group
+	fmt.Fprint(w, "\n"+st.header(r.p.msg(MsgOptions))+"\n")
+	for _, opt := range h.Options {
+		l.printTwoCols(w, st.optionLeft(opt.spec), r.p.specHelp(opt.spec), r.p.specNotes(opt.spec))
 	}
 
-	// write the list of built in options
-	p.printOption(w, &spec{
-		boolean: true,
-		long:    "help",
-		short:   "h",
-		help:    "display this help and exit",
-	})
-	if p.version != "" {
-		p.printOption(w, &spec{
-			boolean: true,
-			long:    "version",
-			help:    "display version and exit",
-		})
+	// write each group of options in its own section
+	for _, g := range h.Groups {
+		fmt.Fprint(w, "\n"+st.header(r.p.msg(MsgGroupOptions, g.Name))+"\n")
+		if g.Description != "" {
+			l.wrap(w, g.Description)
+		}
+		for _, opt := range g.Options {
+			l.printTwoCols(w, st.optionLeft(opt.spec), r.p.specHelp(opt.spec), r.p.specNotes(opt.spec))
+		}
+	}
+
+	// write the list of options inherited from ancestor commands
+	if len(h.GlobalOptions) > 0 {
+		fmt.Fprint(w, "\n"+st.header(r.p.msg(MsgGlobalOptions))+"\n")
+		for _, opt := range h.GlobalOptions {
+			l.printTwoCols(w, st.optionLeft(opt.spec), r.p.specHelp(opt.spec), r.p.specNotes(opt.spec))
+		}
 	}
 
 	// write the list of subcommands
-	if len(cmd.subcommands) > 0 {
-		fmt.Fprint(w, "\nCommands:\n")
-		for _, subcmd := range cmd.subcommands {
-			printTwoCols(w, subcmd.name, subcmd.help, nil)
+	if len(h.Subcommands) > 0 {
+		fmt.Fprint(w, "\n"+st.header(r.p.msg(MsgCommands))+"\n")
+		for _, subcmd := range h.Subcommands {
+			l.printTwoCols(w, st.name(subcmd.cmd.name), r.p.commandHelp(subcmd.cmd), nil)
+		}
+	}
+
+	// write the examples, each followed by its explanation
+	if len(h.Examples) > 0 {
+		fmt.Fprint(w, "\n"+st.header(r.p.msg(MsgExamples))+"\n")
+		for _, example := range h.Examples {
+			fmt.Fprintln(w, "  "+r.p.cmd.name+" "+example.Command)
+			if example.Explanation != "" {
+				l.indent(w, 6, example.Explanation)
+			}
 		}
 	}
+
+	// write the list of environment variables
+	if r.p.config.EnvHelp && len(h.Environment) > 0 {
+		fmt.Fprint(w, "\n"+st.header(r.p.msg(MsgEnvironmentVariables))+"\n")
+		for _, opt := range h.Environment {
+			l.printTwoCols(w, st.name(opt.spec.env), r.p.specHelp(opt.spec), nil)
+		}
+	}
+
+	if h.Epilogue != "" {
+		fmt.Fprint(w, "\n")
+		l.wrap(w, h.Epilogue)
+	}
+	return nil
 }
 
-func (p *Parser) printOption(w io.Writer, spec *spec) {
-	left := synopsis(spec, "--"+spec.long)
+// orderGroups sorts groups according to Config.GroupOrder
+func (p *Parser) orderGroups(groups []*group) []*group {
+	var out []*group
+	used := make(map[*group]bool)
+	for _, name := range p.config.GroupOrder {
+		for _, g := range groups {
+			if g.name == name && !used[g] {
+				out = append(out, g)
+				used[g] = true
+			}
+		}
+	}
+	for _, g := range groups {
+		if !used[g] {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// visibleSpecs gets the positionals and options of a command that are not
+// hidden
+func visibleSpecs(cmd *command) (positionals, options []*spec) {
+	for _, spec := range cmd.specs {
+		switch {
+		case spec.hidden:
+			continue
+		case spec.positional:
+			positionals = append(positionals, spec)
+		default:
+			options = append(options, spec)
+		}
+	}
+	return
+}
+
+// visibleSubcommands gets the subcommands of a command that are not hidden
+func visibleSubcommands(cmd *command) []*command {
+	var out []*command
+	for _, subcmd := range cmd.subcommands {
+		if !subcmd.hidden {
+			out = append(out, subcmd)
+		}
+	}
+	return out
+}
+
+// specNotes gets the bracketed annotations that follow the help text for an
+// option, such as "default: 4" and "env: WORKERS"
+func (p *Parser) specNotes(spec *spec) []string {
+	var notes []string
+	if v := p.defaultValue(spec); v != nil && !spec.secret {
+		notes = append(notes, p.msg(MsgDefaultValue, *v))
+	}
+	if spec.env != "" && !spec.secret {
+		notes = append(notes, p.msg(MsgEnvVar, spec.env))
+	}
+	return notes
+}
+
+// specHelp gets the help text for an option, including a deprecation notice
+func (p *Parser) specHelp(spec *spec) string {
+	return p.withDeprecation(spec.help, spec.deprecated)
+}
+
+// commandHelp gets the summary of a subcommand for lists of commands,
+// including a deprecation notice
+func (p *Parser) commandHelp(cmd *command) string {
+	if cmd.summary != "" {
+		return p.withDeprecation(cmd.summary, cmd.deprecated)
+	}
+	return p.withDeprecation(cmd.help, cmd.deprecated)
+}
+
+// commandDescription gets the description of a command for its own help
+// text, falling back to the help tag of a subcommand
+func commandDescription(cmd *command) string {
+	if cmd.description != "" {
+		return cmd.description
+	}
+	return cmd.help
+}
+
+func (p *Parser) withDeprecation(help, deprecated string) string {
+	if deprecated == "" {
+		return help
+	}
+	if help != "" {
+		help += " "
+	}
+	return help + p.msg(MsgDeprecatedNote, deprecated)
+}
+
+// optionLeft gets the left column of the help text for an option
+func optionLeft(spec *spec) string {
+	return style{}.optionLeft(spec)
+}
+
+// optionLeft gets the styled left column of the help text for an option
+func (s style) optionLeft(spec *spec) string {
+	left := s.synopsis(spec, "--"+spec.long)
 	if spec.short != "" {
-		left += ", " + synopsis(spec, "-"+spec.short)
+		left += ", " + s.synopsis(spec, "-"+spec.short)
 	}
+	return left
+}
 
+// defaultValue gets a string representation of the default value for the
+// given option, or nil if the option has no default
+func (p *Parser) defaultValue(spec *spec) *string {
 	// If spec.dest is not the zero value then a default value has been added.
 	var v reflect.Value
 	if len(spec.dest.fields) > 0 {
@@ -197,14 +525,19 @@ func (p *Parser) printOption(w io.Writer, spec *spec) {
 			}
 		}
 	}
-	printTwoCols(w, left, spec.help, defaultVal)
+	return defaultVal
 }
 
 func synopsis(spec *spec, form string) string {
+	return style{}.synopsis(spec, form)
+}
+
+// synopsis gets the styled form of an option followed by its placeholder
+func (s style) synopsis(spec *spec, form string) string {
 	if spec.boolean {
-		return form
+		return s.name(form)
 	}
-	return form + " " + strings.ToUpper(spec.long)
+	return s.name(form) + " " + s.placeholder(spec.placeholder)
 }
 
 func ptrTo(s string) *string {
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"text/template"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -33,9 +37,9 @@ func (n *NameDotName) MarshalText() (text []byte, err error) {
 }
 
 func TestWriteUsage(t *testing.T) {
-	expectedUsage := "Usage: example [--name NAME] [--value VALUE] [--verbose] [--dataset DATASET] [--optimize OPTIMIZE] [--ids IDS] [--values VALUES] [--workers WORKERS] [--file FILE] INPUT [OUTPUT [OUTPUT ...]]\n"
+	expectedUsage := "Usage: example [--name NAME] [--value N] [--verbose] [--dataset DATASET] [--optimize N] [--ids N] [--values VALUES] [--workers N] [--file FILE] INPUT [OUTPUT [OUTPUT ...]]\n"
 
-	expectedHelp := `Usage: example [--name NAME] [--value VALUE] [--verbose] [--dataset DATASET] [--optimize OPTIMIZE] [--ids IDS] [--values VALUES] [--workers WORKERS] [--file FILE] INPUT [OUTPUT [OUTPUT ...]]
+	expectedHelp := `Usage: example [--name NAME] [--value N] [--verbose] [--dataset DATASET] [--optimize N] [--ids N] [--values VALUES] [--workers N] [--file FILE] INPUT [OUTPUT [OUTPUT ...]]
 
 Positional arguments:
   INPUT
@@ -43,15 +47,13 @@ Positional arguments:
 
 Options:
   --name NAME            name to use [default: Foo Bar]
-  --value VALUE          secret value [default: 42]
+  --value N              secret value [default: 42]
   --verbose, -v          verbosity level
   --dataset DATASET      dataset to use
-  --optimize OPTIMIZE, -O OPTIMIZE
-                         optimization level
-  --ids IDS              Ids
+  --optimize N, -O N     optimization level
+  --ids N                Ids
   --values VALUES        Values [default: [3.14 42 256]]
-  --workers WORKERS, -w WORKERS
-                         number of workers to start
+  --workers N, -w N      number of workers to start [env: WORKERS]
   --file FILE, -f FILE   File with mandatory extension [default: scratch.txt]
   --help, -h             display this help and exit
 `
@@ -72,7 +74,7 @@ Options:
 	args.Value = 42
 	args.Values = []float64{3.14, 42, 256}
 	args.File = &NameDotName{"scratch", "txt"}
-	p, err := NewParser(Config{"example"}, &args)
+	p, err := NewParser(Config{Program: "example"}, &args)
 	require.NoError(t, err)
 
 	os.Args[0] = "example"
@@ -108,7 +110,7 @@ Options:
 	}
 	v := MyEnum(42)
 	args.Name = &v
-	p, err := NewParser(Config{"example"}, &args)
+	p, err := NewParser(Config{Program: "example"}, &args)
 
 	// NB: some might might expect there to be an error here
 	require.NoError(t, err)
@@ -260,3 +262,697 @@ Options:
 	p.WriteHelp(&help)
 	assert.Equal(t, expectedHelp, help.String())
 }
+
+type longDescribed struct{}
+
+// Description returns a description that needs to be wrapped
+func (longDescribed) Description() string {
+	return "this program does this and that, and its description is long enough to be wrapped"
+}
+
+func TestUsageWrapped(t *testing.T) {
+	expectedHelp := `this program does this and that, and its description is long enough to be
+wrapped
+Usage: example [--name NAME] [--verbose] [--optimize N]
+               [--workers-per-node N] INPUT
+
+Positional arguments:
+  INPUT                  the input file
+
+Options:
+  --name NAME            name to use, which is explained in enough detail
+                         that it needs more than one line [default: Foo Bar]
+  --verbose, -v          verbosity level
+  --optimize N, -O N     optimization level
+  --workers-per-node N   number of workers
+  --help, -h             display this help and exit
+`
+	var args struct {
+		longDescribed
+		Input    string `arg:"positional" help:"the input file"`
+		Name     string `help:"name to use, which is explained in enough detail that it needs more than one line"`
+		Verbose  bool   `arg:"-v" help:"verbosity level"`
+		Optimize int    `arg:"-O" help:"optimization level"`
+		Workers  int    `arg:"--workers-per-node" help:"number of workers"`
+	}
+	args.Name = "Foo Bar"
+	p, err := NewParser(Config{Program: "example", HelpWidth: 76}, &args)
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+}
+
+func TestUsageColumnsWithoutTerminal(t *testing.T) {
+	expectedHelp := `Usage: example [--name NAME]
+
+Options:
+  --name NAME   name to use, which is explained in
+                enough detail that it needs more
+                than one line
+  --help, -h    display this help and exit
+`
+	var args struct {
+		Name string `help:"name to use, which is explained in enough detail that it needs more than one line"`
+	}
+	lookup := func(name string) (string, bool) {
+		if name == "COLUMNS" {
+			return "50", true
+		}
+		return "", false
+	}
+	p, err := NewParser(Config{Program: "example", LookupEnv: lookup, ColumnsWhenPiped: true}, &args)
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+
+	// without the option, COLUMNS only applies to terminals
+	p, err = NewParser(Config{Program: "example", LookupEnv: lookup}, &args)
+	require.NoError(t, err)
+
+	help.Reset()
+	p.WriteHelp(&help)
+	assert.NotEqual(t, expectedHelp, help.String())
+	assert.Contains(t, help.String(), "  --name NAME            name to use, which is explained in enough detail that it needs more than one line\n")
+}
+
+func TestUsageWideCharacters(t *testing.T) {
+	expectedHelp := `Usage: example [--名前 名前]
+
+Options:
+  --名前 名前            name to use
+  --help, -h             display this help and exit
+`
+	var args struct {
+		Name string `arg:"--名前" help:"name to use"`
+	}
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+}
+
+func TestStringWidth(t *testing.T) {
+	assert.Equal(t, 5, stringWidth("hello"))
+	assert.Equal(t, 4, stringWidth("名前"))
+	assert.Equal(t, 4, stringWidth("café"))
+	assert.Equal(t, 4, stringWidth("café"))
+	assert.Equal(t, 9, stringWidth("\x1b[36m--workers\x1b[0m"))
+}
+
+type databaseOptions struct {
+	Host string `help:"database host"`
+	Port int    `help:"database port"`
+}
+
+func (databaseOptions) GroupDescription() string {
+	return "Connection settings for the metadata database."
+}
+
+type logOptions struct {
+	LogFile string `help:"log file"`
+}
+
+func TestUsageWithGroups(t *testing.T) {
+	expectedHelp := `Usage: example [--verbose] [--host HOST] [--port N] [--logfile LOGFILE] [--trace]
+
+Options:
+  --verbose              verbosity level
+  --help, -h             display this help and exit
+
+Logging options:
+  --logfile LOGFILE      log file
+  --trace                trace all calls
+
+Database options:
+Connection settings for the metadata database.
+  --host HOST            database host
+  --port N               database port
+`
+	var args struct {
+		Verbose         bool `help:"verbosity level"`
+		databaseOptions `group:"Database"`
+		logOptions      `group:"Logging"`
+		Trace           bool `group:"Logging" help:"trace all calls"`
+	}
+	p, err := NewParser(Config{Program: "example", GroupOrder: []string{"Logging"}}, &args)
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+}
+
+type serverArgs struct {
+	Listen string `help:"address to listen on"`
+}
+
+func TestUsageWithEmbeddedGroups(t *testing.T) {
+	expectedHelp := `Usage: example [--verbose] [--host HOST] [--port N] [--listen LISTEN]
+
+Options:
+  --verbose              verbosity level
+  --help, -h             display this help and exit
+
+Database options:
+Connection settings for the metadata database.
+  --host HOST            database host
+  --port N               database port
+
+Server options:
+  --listen LISTEN        address to listen on
+`
+	var args struct {
+		Verbose bool `help:"verbosity level"`
+		databaseOptions
+		serverArgs
+	}
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+}
+
+type databaseProgram struct {
+	databaseOptions
+}
+
+// Description returns the same text as the description of the group
+func (databaseProgram) Description() string {
+	return "Connection settings for the metadata database."
+}
+
+type cacheOptions struct {
+	CacheSize int
+}
+
+// Description is promoted to the command, since it is not a group description
+func (cacheOptions) Description() string {
+	return "this program caches things"
+}
+
+func TestUsageGroupAndCommandDescriptions(t *testing.T) {
+	var args databaseProgram
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+	assert.Equal(t, "Connection settings for the metadata database.", p.cmd.description)
+	require.Len(t, p.cmd.groups, 1)
+	assert.Equal(t, "Connection settings for the metadata database.", p.cmd.groups[0].description)
+
+	var promoted struct {
+		cacheOptions
+	}
+	p, err = NewParser(Config{Program: "example"}, &promoted)
+	require.NoError(t, err)
+	assert.Equal(t, "this program caches things", p.cmd.description)
+	require.Len(t, p.cmd.groups, 1)
+	assert.Equal(t, "Cache", p.cmd.groups[0].name)
+	assert.Equal(t, "", p.cmd.groups[0].description)
+}
+
+func TestGroupName(t *testing.T) {
+	assert.Equal(t, "Database", groupName(reflect.TypeOf(databaseOptions{})))
+	assert.Equal(t, "Server", groupName(reflect.TypeOf(serverArgs{})))
+	assert.Equal(t, "Log", groupName(reflect.TypeOf(logOptions{})))
+	assert.Equal(t, "Described", groupName(reflect.TypeOf(described{})))
+}
+
+func TestUsageCompact(t *testing.T) {
+	expectedUsage := "Usage: example [options] --name NAME INPUT\n"
+	var args struct {
+		Input   string `arg:"positional"`
+		Name    string `arg:"required"`
+		Verbose bool
+		Dataset string
+	}
+	p, err := NewParser(Config{Program: "example", CompactUsage: 1}, &args)
+	require.NoError(t, err)
+
+	var usage bytes.Buffer
+	p.WriteUsage(&usage)
+	assert.Equal(t, expectedUsage, usage.String())
+}
+
+func TestUsageForNestedSubcommand(t *testing.T) {
+	expectedHelp := `Usage: example [--quiet] remote [--verbose] add [--force] NAME
+
+Positional arguments:
+  NAME                   name of the remote
+
+Options:
+  --force                overwrite existing remote
+  --help, -h             display this help and exit
+
+Global options:
+  --quiet, -q            suppress output
+  --verbose, -v          show details
+`
+	type addCmd struct {
+		Force bool   `help:"overwrite existing remote"`
+		Name  string `arg:"positional" help:"name of the remote"`
+	}
+	type remoteCmd struct {
+		Verbose bool    `arg:"-v" help:"show details"`
+		Add     *addCmd `arg:"subcommand"`
+	}
+	var args struct {
+		Quiet  bool       `arg:"-q" help:"suppress output"`
+		Remote *remoteCmd `arg:"subcommand"`
+	}
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	_ = p.Parse([]string{"remote", "add", "--help"})
+	var help bytes.Buffer
+	p.writeHelpForCommand(&help, p.lastCmd)
+	assert.Equal(t, expectedHelp, help.String())
+}
+
+func TestUsageWithHiddenAndDeprecated(t *testing.T) {
+	expectedHelp := `Usage: example [--output OUTPUT] [--out OUT]
+
+Options:
+  --output OUTPUT        output file
+  --out OUT              output file (deprecated: use --output instead)
+  --help, -h             display this help and exit
+
+Commands:
+  get                    fetch an item
+  fetch                  (deprecated: use get instead)
+`
+	var args struct {
+		Output string    `help:"output file"`
+		Out    string    `help:"output file" deprecated:"use --output instead"`
+		Debug  bool      `arg:"hidden"`
+		Get    *struct{} `arg:"subcommand" help:"fetch an item"`
+		Fetch  *struct{} `arg:"subcommand" deprecated:"use get instead"`
+		Dump   *struct{} `arg:"hidden,subcommand"`
+	}
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+
+	err = p.Parse([]string{"--debug", "dump"})
+	require.NoError(t, err)
+	assert.True(t, args.Debug)
+	assert.NotNil(t, args.Dump)
+}
+
+type compressionLevel int
+
+func (c *compressionLevel) UnmarshalArg(b []byte) error {
+	return nil
+}
+
+func (compressionLevel) Placeholder() string {
+	return "1-9"
+}
+
+func TestUsageWithPlaceholders(t *testing.T) {
+	expectedHelp := `Usage: example [--optimize LEVEL] [--timeout DURATION] [--level 1-9] [--levels 1-9] [--count N] SRC [DST [DST ...]]
+
+Positional arguments:
+  SRC
+  DST
+
+Options:
+  --optimize LEVEL, -O LEVEL
+                         optimization level
+  --timeout DURATION
+  --level 1-9
+  --levels 1-9
+  --count N
+  --help, -h             display this help and exit
+`
+	var args struct {
+		Input    string   `arg:"positional" placeholder:"SRC"`
+		Output   []string `arg:"positional" placeholder:"DST"`
+		Optimize int      `arg:"-O" placeholder:"LEVEL" help:"optimization level"`
+		Timeout  time.Duration
+		Level    compressionLevel
+		Levels   []*compressionLevel
+		Count    uint
+	}
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+}
+
+func TestUsageWrappedWithPlaceholders(t *testing.T) {
+	expectedHelp := `Usage: example [--count N] [--timeout DURATION]
+               [--archive COMPRESSED-ARCHIVE]
+               [--level 1-9]
+
+Options:
+  --count N     number of items
+  --timeout DURATION
+                how long to wait before giving up
+                on the server
+  --archive COMPRESSED-ARCHIVE
+                archive to read
+  --level 1-9   compression level
+  --help, -h    display this help and exit
+`
+	var args struct {
+		Count   int              `help:"number of items"`
+		Timeout time.Duration    `help:"how long to wait before giving up on the server"`
+		Archive string           `placeholder:"COMPRESSED-ARCHIVE" help:"archive to read"`
+		Level   compressionLevel `help:"compression level"`
+	}
+	p, err := NewParser(Config{Program: "example", HelpWidth: 50}, &args)
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+}
+
+func TestUsageWithTemplateRenderer(t *testing.T) {
+	expectedHelp := `ACME EXAMPLE TOOL
+usage: example [--name NAME] [--verbose] INPUT
+
+arguments:
+  INPUT                the input file
+options:
+  --name NAME          name to use
+  --verbose, -v        verbosity level
+  --help, -h           display this help and exit
+-- see https://example.com/docs --
+`
+	tmpl := template.Must(template.New("help").Funcs(HelpFuncs()).Funcs(template.FuncMap{"upper": strings.ToUpper}).Parse(
+		`ACME {{.Command.Name | printf "%s tool" | upper}}
+usage: {{.Usage}}
+
+arguments:
+{{range .Positionals}}  {{pad 20 .Placeholder}} {{.Help}}
+{{end}}options:
+{{range .Options}}  {{pad 20 (left .)}} {{.Help}}
+{{end}}-- see https://example.com/docs --
+`))
+
+	var args struct {
+		Input   string `arg:"positional" help:"the input file"`
+		Name    string `help:"name to use"`
+		Verbose bool   `arg:"-v" help:"verbosity level"`
+	}
+	config := Config{
+		Program:      "example",
+		HelpRenderer: TemplateRenderer{Help: tmpl},
+	}
+	p, err := NewParser(config, &args)
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+
+	var usage bytes.Buffer
+	p.WriteUsage(&usage)
+	assert.Equal(t, "Usage: example [--name NAME] [--verbose] INPUT\n", usage.String())
+}
+
+func TestUsageWithTemplateRendererUsageOnly(t *testing.T) {
+	expectedHelp := `run: example [--name NAME] INPUT
+
+Positional arguments:
+  INPUT                  the input file
+
+Options:
+  --name NAME            name to use
+  --help, -h             display this help and exit
+`
+	tmpl := template.Must(template.New("usage").Parse("run: {{.Usage}}\n"))
+
+	var args struct {
+		Input string `arg:"positional" help:"the input file"`
+		Name  string `help:"name to use"`
+	}
+	config := Config{
+		Program:      "example",
+		HelpRenderer: TemplateRenderer{Usage: tmpl},
+	}
+	p, err := NewParser(config, &args)
+	require.NoError(t, err)
+
+	var usage bytes.Buffer
+	p.WriteUsage(&usage)
+	assert.Equal(t, "run: example [--name NAME] INPUT\n", usage.String())
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+}
+
+type countingRenderer struct {
+	usages, helps int
+}
+
+func (r *countingRenderer) RenderUsage(w io.Writer, h *Help) error {
+	r.usages++
+	return nil
+}
+
+func (r *countingRenderer) RenderHelp(w io.Writer, h *Help) error {
+	r.helps++
+	return errors.New("out of ink")
+}
+
+func TestUsageWithCustomRenderer(t *testing.T) {
+	var r countingRenderer
+	var stderr bytes.Buffer
+	p, err := NewParser(Config{HelpRenderer: &r, Stderr: &stderr}, &struct{}{})
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteUsage(&help)
+	p.WriteHelp(&help)
+	assert.Equal(t, 1, r.usages)
+	assert.Equal(t, 1, r.helps)
+	assert.Equal(t, "", help.String())
+	assert.Equal(t, "error: cannot render help: out of ink\n", stderr.String())
+}
+
+func TestUsageWithColor(t *testing.T) {
+	expectedUsage := "\x1b[1mUsage:\x1b[0m example [\x1b[36m--workers\x1b[0m \x1b[4mN\x1b[0m] \x1b[4mSRC\x1b[0m\n"
+
+	expectedHelp := "\x1b[1mUsage:\x1b[0m example [\x1b[36m--workers\x1b[0m \x1b[4mN\x1b[0m] \x1b[4mSRC\x1b[0m\n" +
+		"\n" +
+		"\x1b[1mPositional arguments:\x1b[0m\n" +
+		"  \x1b[4mSRC\x1b[0m\n" +
+		"\n" +
+		"\x1b[1mOptions:\x1b[0m\n" +
+		"  \x1b[36m--workers\x1b[0m \x1b[4mN\x1b[0m, \x1b[36m-w\x1b[0m \x1b[4mN\x1b[0m      number of workers\n" +
+		"  \x1b[36m--help\x1b[0m, \x1b[36m-h\x1b[0m             display this help and exit\n"
+
+	var args struct {
+		Src     string `arg:"positional,required"`
+		Workers int    `arg:"-w" help:"number of workers"`
+	}
+	p, err := NewParser(Config{Program: "example", Color: ColorAlways}, &args)
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+
+	var usage bytes.Buffer
+	p.WriteUsage(&usage)
+	assert.Equal(t, expectedUsage, usage.String())
+}
+
+func TestUsageColorAuto(t *testing.T) {
+	var args struct {
+		Workers int `arg:"-w"`
+	}
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	// colors are only used automatically for terminals
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.NotContains(t, help.String(), "\x1b[")
+	assert.False(t, p.styleFor(&help).color)
+
+	p.config.Color = ColorNever
+	assert.False(t, p.styleFor(os.Stdout).color)
+}
+
+func TestUsageNoColorEnv(t *testing.T) {
+	setenv(t, "NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+	p, err := NewParser(Config{}, &struct{}{})
+	require.NoError(t, err)
+	assert.False(t, p.styleFor(os.Stdout).color)
+
+	// NO_COLOR does not override an explicit request for colors
+	p.config.Color = ColorAlways
+	assert.True(t, p.styleFor(os.Stdout).color)
+}
+
+func TestFailWithColor(t *testing.T) {
+	var output bytes.Buffer
+	originalStderr, originalExit := stderr, osExit
+	defer func() { stderr, osExit = originalStderr, originalExit }()
+	osExit = func(int) {}
+
+	p, err := NewParser(Config{Program: "example", Color: ColorAlways}, &struct{}{})
+	require.NoError(t, err)
+
+	// stderr is an *os.File, so write through a pipe to capture the output
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stderr = w
+	p.Fail("something went wrong")
+	w.Close()
+	_, err = output.ReadFrom(r)
+	require.NoError(t, err)
+	assert.Equal(t, "\x1b[1mUsage:\x1b[0m example\n\x1b[1;31merror:\x1b[0m something went wrong\n", output.String())
+}
+
+func TestUsageWithEnvironment(t *testing.T) {
+	expectedHelp := `Usage: example [--workers N] [--token TOKEN]
+
+Options:
+  --workers N            number of workers [default: 4] [env: WORKERS]
+  --token TOKEN          access token
+  --help, -h             display this help and exit
+
+Commands:
+  push                   push changes
+
+Environment variables:
+  WORKERS                number of workers
+  REMOTE                 remote to push to
+`
+
+	var args struct {
+		Workers int    `arg:"env" help:"number of workers"`
+		Token   string `arg:"env,secret" help:"access token"`
+		Push    *struct {
+			Remote string `arg:"env" help:"remote to push to"`
+			Debug  string `arg:"hidden,env"`
+		} `arg:"subcommand" help:"push changes"`
+	}
+	args.Workers = 4
+	args.Token = "hunter2"
+	p, err := NewParser(Config{Program: "example", EnvHelp: true}, &args)
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+}
+
+func TestUsageWithEnvironmentForSubcommand(t *testing.T) {
+	expectedHelp := `push changes
+Usage: example [--workers N] push [--remote REMOTE]
+
+Options:
+  --remote REMOTE        remote to push to [env: REMOTE]
+  --help, -h             display this help and exit
+
+Global options:
+  --workers N            number of workers [env: WORKERS]
+
+Environment variables:
+  WORKERS                number of workers
+  REMOTE                 remote to push to
+`
+
+	var args struct {
+		Workers int `arg:"env" help:"number of workers"`
+		Push    *struct {
+			Remote string `arg:"env" help:"remote to push to"`
+		} `arg:"subcommand" help:"push changes"`
+	}
+	p, err := NewParser(Config{Program: "example", EnvHelp: true}, &args)
+	require.NoError(t, err)
+
+	_ = p.Parse([]string{"push", "--help"})
+	var help bytes.Buffer
+	p.writeHelpForCommand(&help, p.lastCmd)
+	assert.Equal(t, expectedHelp, help.String())
+}
+
+type describedPushCmd struct {
+	Force bool `help:"overwrite the remote branch"`
+}
+
+func (describedPushCmd) Summary() string {
+	return "upload changes"
+}
+
+func (describedPushCmd) Description() string {
+	return "push uploads local commits to the remote repository"
+}
+
+func (describedPushCmd) Version() string {
+	return "push 2.0"
+}
+
+func TestUsageWithSubcommandDescriptions(t *testing.T) {
+	expectedHelp := `example 3.2.1
+Usage: example
+
+Options:
+  --help, -h             display this help and exit
+  --version              display version and exit
+
+Commands:
+  push                   upload changes
+  pull                   download changes
+`
+
+	expectedPushHelp := `push uploads local commits to the remote repository
+push 2.0
+Usage: example push [--force]
+
+Options:
+  --force                overwrite the remote branch
+  --help, -h             display this help and exit
+  --version              display version and exit
+`
+
+	expectedPullHelp := `download changes
+example 3.2.1
+Usage: example pull
+
+Options:
+  --help, -h             display this help and exit
+  --version              display version and exit
+`
+
+	var args struct {
+		versioned
+		Push *describedPushCmd `arg:"subcommand" help:"ignored because Summary is implemented"`
+		Pull *struct{}         `arg:"subcommand" help:"download changes"`
+	}
+	p, err := NewParser(Config{Program: "example"}, &args)
+	require.NoError(t, err)
+
+	var help bytes.Buffer
+	p.WriteHelp(&help)
+	assert.Equal(t, expectedHelp, help.String())
+
+	var pushHelp bytes.Buffer
+	p.writeHelpForCommand(&pushHelp, p.cmd.subcommands[0])
+	assert.Equal(t, expectedPushHelp, pushHelp.String())
+
+	// the version is inherited and the description falls back to the help tag
+	var pullHelp bytes.Buffer
+	p.writeHelpForCommand(&pullHelp, p.cmd.subcommands[1])
+	assert.Equal(t, expectedPullHelp, pullHelp.String())
+}
@@ -0,0 +1,114 @@
+package arg
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ValueDirPrecedence sets how values from Config.ValueDirs rank against the
+// environment and the command line
+type ValueDirPrecedence int
+
+const (
+	// ValueDirBelowEnv lets environment variables override values from files
+	ValueDirBelowEnv ValueDirPrecedence = iota
+	// ValueDirAboveEnv lets values from files override environment variables,
+	// while the command line overrides both
+	ValueDirAboveEnv
+	// ValueDirAboveCommandLine lets values from files override the command line
+	ValueDirAboveCommandLine
+)
+
+// loadValueDirs finds the files in the directories in Config.ValueDirs, so
+// that they can be matched against options
+func (p *Parser) loadValueDirs() error {
+	p.valueFiles = nil
+	for _, dir := range p.config.ValueDirs {
+		if dir == "" {
+			continue
+		}
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return p.errorf(MsgValueDirError, dir, err)
+		}
+		for _, entry := range entries {
+			// kubernetes keeps its own bookkeeping in entries such as "..data"
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			if p.valueFiles == nil {
+				p.valueFiles = make(map[string]string)
+			}
+			p.valueFiles[entry.Name()] = filepath.Join(dir, entry.Name())
+		}
+	}
+	return nil
+}
+
+// captureValueDirs sets options from the files found by loadValueDirs. A file
+// matches an option if its name is the environment variable or the long name
+// of the option.
+func (p *Parser) captureValueDirs(specs []*spec, wasPresent map[*spec]bool) error {
+	for _, spec := range specs {
+		path, found := p.valueFiles[spec.env]
+		if !found || spec.env == "" {
+			path, found = p.valueFiles[spec.long]
+		}
+		if !found {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return p.errorf(MsgValueFileError, path, err)
+		}
+		value := strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+
+		name := spec.long
+		if !spec.positional {
+			name = "--" + spec.long
+		}
+		if spec.multiple {
+			// as with environment variables, multiple values are a CSV string
+			values, err := csv.NewReader(strings.NewReader(value)).Read()
+			if err == nil {
+				err = setSlice(p.val(spec.dest), values, true)
+			}
+			if err != nil {
+				return p.errorf(MsgInvalidFileValue, path, name, err)
+			}
+		} else if err := parseValue(p.val(spec.dest), value); err != nil {
+			return p.errorf(MsgInvalidFileValue, path, name, err)
+		}
+		wasPresent[spec] = true
+		if spec.deprecated != "" {
+			p.warn(p.msg(MsgDeprecatedValueFile, path, spec.deprecated))
+		}
+	}
+	return nil
+}
+
+// captureEnvironment sets options from the environment and from the files in
+// Config.ValueDirs, unless those files take precedence over the command line
+func (p *Parser) captureEnvironment(specs []*spec, wasPresent map[*spec]bool) error {
+	switch p.config.ValueDirPrecedence {
+	case ValueDirBelowEnv:
+		if err := p.captureValueDirs(specs, wasPresent); err != nil {
+			return err
+		}
+		return p.captureEnvVars(specs, wasPresent)
+	case ValueDirAboveEnv:
+		if err := p.captureEnvVars(specs, wasPresent); err != nil {
+			return err
+		}
+		return p.captureValueDirs(specs, wasPresent)
+	default:
+		return p.captureEnvVars(specs, wasPresent)
+	}
+}
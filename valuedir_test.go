@@ -0,0 +1,161 @@
+package arg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeValueDir creates a temporary directory holding the given files
+func writeValueDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "go-arg-values")
+	require.NoError(t, err)
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	return dir
+}
+
+func noEnv(string) (string, bool) { return "", false }
+
+func TestValueDirs(t *testing.T) {
+	dir := writeValueDir(t, map[string]string{
+		"DB_PASSWORD": "hunter2\n",
+		"workers":     "4\r\n",
+		"tags":        "a,b",
+		"..data":      "ignored",
+	})
+	defer os.RemoveAll(dir)
+
+	var args struct {
+		Password string `arg:"--password,env:DB_PASSWORD"`
+		Workers  int
+		Tags     []string
+		Other    string
+	}
+	p, err := NewParser(Config{ValueDirs: []string{"", dir, "does-not-exist"}, LookupEnv: noEnv}, &args)
+	require.NoError(t, err)
+	require.NoError(t, p.Parse(nil))
+	assert.Equal(t, "hunter2", args.Password)
+	assert.Equal(t, 4, args.Workers)
+	assert.Equal(t, []string{"a", "b"}, args.Tags)
+	assert.Equal(t, "", args.Other)
+}
+
+func TestValueDirsSubcommand(t *testing.T) {
+	dir := writeValueDir(t, map[string]string{"REMOTE": "origin"})
+	defer os.RemoveAll(dir)
+
+	var args struct {
+		Push *struct {
+			Remote string `arg:"env"`
+		} `arg:"subcommand"`
+	}
+	p, err := NewParser(Config{ValueDirs: []string{dir}, LookupEnv: noEnv}, &args)
+	require.NoError(t, err)
+	require.NoError(t, p.Parse([]string{"push"}))
+	assert.Equal(t, "origin", args.Push.Remote)
+}
+
+func TestValueDirsPrecedence(t *testing.T) {
+	dir := writeValueDir(t, map[string]string{"A": "file", "B": "file", "C": "file"})
+	defer os.RemoveAll(dir)
+	env := func(name string) (string, bool) {
+		if name == "B" || name == "C" {
+			return "env", true
+		}
+		return "", false
+	}
+
+	for _, test := range []struct {
+		precedence ValueDirPrecedence
+		a, b, c    string
+	}{
+		{ValueDirBelowEnv, "file", "env", "flag"},
+		{ValueDirAboveEnv, "file", "file", "flag"},
+		{ValueDirAboveCommandLine, "file", "file", "file"},
+	} {
+		var args struct {
+			A string `arg:"env"`
+			B string `arg:"env"`
+			C string `arg:"env"`
+		}
+		p, err := NewParser(Config{ValueDirs: []string{dir}, ValueDirPrecedence: test.precedence, LookupEnv: env}, &args)
+		require.NoError(t, err)
+		require.NoError(t, p.Parse([]string{"-c", "flag"}))
+		assert.Equal(t, test.a, args.A)
+		assert.Equal(t, test.b, args.B)
+		assert.Equal(t, test.c, args.C)
+	}
+}
+
+func TestValueDirsSatisfyRequired(t *testing.T) {
+	dir := writeValueDir(t, map[string]string{"token": "secret"})
+	defer os.RemoveAll(dir)
+
+	var args struct {
+		Token string `arg:"required"`
+	}
+	p, err := NewParser(Config{ValueDirs: []string{dir}}, &args)
+	require.NoError(t, err)
+	require.NoError(t, p.Parse(nil))
+	assert.Equal(t, "secret", args.Token)
+}
+
+func TestValueDirsInvalidValue(t *testing.T) {
+	dir := writeValueDir(t, map[string]string{"workers": "many\n"})
+	defer os.RemoveAll(dir)
+
+	var args struct {
+		Workers int
+	}
+	p, err := NewParser(Config{ValueDirs: []string{dir}}, &args)
+	require.NoError(t, err)
+	err = p.Parse(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), filepath.Join(dir, "workers")+": error processing --workers: ")
+}
+
+func TestValueDirsDeprecatedOption(t *testing.T) {
+	dir := writeValueDir(t, map[string]string{"WORKERS": "4"})
+	defer os.RemoveAll(dir)
+
+	for _, precedence := range []ValueDirPrecedence{ValueDirBelowEnv, ValueDirAboveEnv} {
+		var args struct {
+			Workers int `arg:"env" deprecated:"use --jobs instead"`
+		}
+		p, err := NewParser(Config{
+			ValueDirs:          []string{dir},
+			ValueDirPrecedence: precedence,
+			LookupEnv:          noEnv,
+			Stderr:             ioutil.Discard,
+		}, &args)
+		require.NoError(t, err)
+		require.NoError(t, p.Parse([]string{"--workers", "8"}))
+		assert.Equal(t, 8, args.Workers)
+		assert.Equal(t, []string{
+			"value file " + filepath.Join(dir, "WORKERS") + " is deprecated: use --jobs instead",
+			"--workers is deprecated: use --jobs instead",
+		}, p.Warnings())
+	}
+}
+
+func TestValueDirsUnreadable(t *testing.T) {
+	dir := writeValueDir(t, nil)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0600))
+
+	var args struct {
+		Workers int
+	}
+	p, err := NewParser(Config{ValueDirs: []string{file}}, &args)
+	require.NoError(t, err)
+	err = p.Parse(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error reading value directory "+file+": ")
+}
@@ -0,0 +1,89 @@
+package arg
+
+import (
+	"strings"
+	"unicode"
+)
+
+// wideRanges lists the code points that occupy two columns on a terminal
+// (east asian wide and fullwidth characters, and emoji)
+var wideRanges = []struct{ lo, hi rune }{
+	{0x1100, 0x115F},
+	{0x2E80, 0x303E},
+	{0x3041, 0x33FF},
+	{0x3400, 0x4DBF},
+	{0x4E00, 0x9FFF},
+	{0xA000, 0xA4CF},
+	{0xAC00, 0xD7A3},
+	{0xF900, 0xFAFF},
+	{0xFE30, 0xFE4F},
+	{0xFF00, 0xFF60},
+	{0xFFE0, 0xFFE6},
+	{0x1F300, 0x1F64F},
+	{0x1F900, 0x1F9FF},
+	{0x20000, 0x2FFFD},
+	{0x30000, 0x3FFFD},
+}
+
+// runeWidth gets the number of terminal columns occupied by a rune
+func runeWidth(r rune) int {
+	if r == 0 || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	for _, rng := range wideRanges {
+		if r >= rng.lo && r <= rng.hi {
+			return 2
+		}
+	}
+	return 1
+}
+
+// stringWidth gets the number of terminal columns occupied by a string,
+// ignoring ANSI escape sequences
+func stringWidth(s string) int {
+	var n int
+	var escape bool
+	for _, r := range s {
+		switch {
+		case escape:
+			escape = r < '@' || r > '~' || r == '['
+		case r == '\x1b':
+			escape = true
+		default:
+			n += runeWidth(r)
+		}
+	}
+	return n
+}
+
+// wrapText breaks text into lines no wider than the given width, breaking at
+// spaces. Existing line breaks are preserved. Words wider than the limit are
+// placed on a line by themselves.
+func wrapText(s string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		lines = append(lines, wrapWords(strings.Fields(paragraph), width, width)...)
+	}
+	return lines
+}
+
+// wrapWords joins words with spaces into lines, where the first line is no
+// wider than first and subsequent lines are no wider than rest
+func wrapWords(words []string, first, rest int) []string {
+	var lines []string
+	var cur string
+	limit := first
+	for _, word := range words {
+		switch {
+		case cur == "":
+			cur = word
+		case stringWidth(cur)+1+stringWidth(word) <= limit:
+			cur += " " + word
+		default:
+			lines = append(lines, cur)
+			cur = word
+			limit = rest
+		}
+	}
+	return append(lines, cur)
+}